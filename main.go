@@ -2,16 +2,20 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"path"
 	"path/filepath"
 	"sort"
+	"strconv"
 	"strings"
-	"unicode"
 
 	"github.com/alecthomas/kong"
 
 	"github.com/flosch/pongo2"
+	"gopkg.in/yaml.v2"
 
 	"github.com/aquasecurity/openapi-generator/traverser"
 )
@@ -19,13 +23,16 @@ import (
 var cli struct {
 	// nolint: govet
 	Parse struct {
-		Pretty bool     `flag help:"pretty print"`
-		Docs   []string `arg help:"document path(s)"`
-	} `cmd help:"Parse specification, print JSON representation of API"`
+		Pretty bool     `flag:"" help:"pretty print"`
+		Docs   []string `arg:"" help:"document path(s)"`
+	} `cmd:"" help:"Parse specification, print JSON representation of API"`
 	Generate struct {
-		Template string   `arg help:"template path"`
-		Docs     []string `arg help:"document path(s)"`
-	} `cmd help:"Generate code"`
+		Template string   `arg:"" help:"template path"`
+		Docs     []string `arg:"" help:"document path(s)"`
+	} `cmd:"" help:"Generate code"`
+	Convert struct {
+		Doc string `arg:"" help:"Swagger 2.0 document path"`
+	} `cmd:"" help:"Convert a Swagger 2.0 specification to OpenAPI 3, print it as YAML"`
 }
 
 func main() {
@@ -36,6 +43,8 @@ func main() {
 		err = parse()
 	case "generate <template> <docs>":
 		err = generate()
+	case "convert <doc>":
+		err = convert()
 	default:
 		err = fmt.Errorf("invalid/unimplemented command %s", ctx.Command())
 	}
@@ -51,12 +60,24 @@ func mergeSpecs(docs []string) (api traverser.API, err error) {
 		RefDocs: make(map[string]traverser.API),
 	}
 
+	// documents referenced by several specs are only loaded once
+	resolver := traverser.NewResolver()
+
 	for i, doc := range docs {
-		this, err := loadAndParse(doc)
-		if err != nil {
+		this, err := traverser.ParseFile(doc, resolver)
+		var srcErr *traverser.SourceError
+		if errors.As(err, &srcErr) {
+			// located errors already name the document
+			return api, err
+		} else if err != nil {
 			return api, fmt.Errorf("failed parsing %s: %w", doc, err)
 		}
 
+		// warnings are located in the document they're about
+		for _, warning := range this.Warnings {
+			fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+		}
+
 		for i, schema := range this.Schemas {
 			schema.Source = doc
 			this.Schemas[i] = schema
@@ -65,6 +86,10 @@ func mergeSpecs(docs []string) (api traverser.API, err error) {
 			method.Source = doc
 			this.Methods[i] = method
 		}
+		for i, method := range this.Webhooks {
+			method.Source = doc
+			this.Webhooks[i] = method
+		}
 		for i, c := range this.Consts {
 			c.Source = doc
 			this.Consts[i] = c
@@ -76,13 +101,31 @@ func mergeSpecs(docs []string) (api traverser.API, err error) {
 			api.GoName = this.GoName
 			api.JsName = this.JsName
 			api.Version = this.Version
+			api.OpenAPI = this.OpenAPI
 			api.Contact = this.Contact
 			api.Servers = append(api.Servers, this.Servers...)
+			api.Security = this.Security
+		}
+
+		for _, imp := range this.Imports {
+			if !hasImport(api.Imports, imp.Dir) {
+				imp.Path = importPath(imp.Dir)
+				api.Imports = append(api.Imports, imp)
+			}
+		}
+
+		for _, tag := range this.Tags {
+			if !hasTag(api.Tags, tag.APIName) {
+				api.Tags = append(api.Tags, tag)
+			}
 		}
 
 		api.Methods = append(api.Methods, this.Methods...)
+		api.Webhooks = append(api.Webhooks, this.Webhooks...)
 		api.Schemas = append(api.Schemas, this.Schemas...)
 		api.Consts = append(api.Consts, this.Consts...)
+		api.SecuritySchemes = append(api.SecuritySchemes, this.SecuritySchemes...)
+		api.Warnings = append(api.Warnings, this.Warnings...)
 		for key, val := range this.RefDocs {
 			api.RefDocs[key] = val
 		}
@@ -103,6 +146,58 @@ func mergeSpecs(docs []string) (api traverser.API, err error) {
 	return api, nil
 }
 
+func hasTag(tags []traverser.Tag, name string) bool {
+	for _, tag := range tags {
+		if tag.APIName == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasImport(imports []traverser.Import, dir string) bool {
+	for _, imp := range imports {
+		if imp.Dir == dir {
+			return true
+		}
+	}
+
+	return false
+}
+
+// importPath returns the import path of the package in a directory, based on
+// the path of the Go module containing it, or an empty string if the
+// directory isn't part of a module
+func importPath(dir string) string {
+	for root := dir; ; root = filepath.Dir(root) {
+		data, err := ioutil.ReadFile(filepath.Join(root, "go.mod"))
+		if err == nil {
+			rel, err := filepath.Rel(root, dir)
+			if err != nil {
+				return ""
+			}
+			return path.Join(modfileModule(data), filepath.ToSlash(rel))
+		}
+
+		if filepath.Dir(root) == root {
+			return ""
+		}
+	}
+}
+
+// modfileModule returns the module path declared by a go.mod file
+func modfileModule(data []byte) string {
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 2 && fields[0] == "module" {
+			return strings.Trim(fields[1], `"`)
+		}
+	}
+
+	return ""
+}
+
 func parse() (err error) {
 	// load and parse the API specification
 	api, err := mergeSpecs(cli.Parse.Docs)
@@ -139,6 +234,8 @@ func generate() (err error) {
 		"api":             api,
 		"comment":         comment,
 		"wrapped_comment": wrappedComment,
+		"number":          number,
+		"quote":           strconv.Quote,
 	}, os.Stdout)
 	if err != nil {
 		return fmt.Errorf("failed generating code: %w", err)
@@ -147,20 +244,20 @@ func generate() (err error) {
 	return nil
 }
 
-func loadAndParse(path string) (api traverser.API, err error) {
-	// load the OpenAPI document
-	var doc traverser.Map
-	if strings.HasSuffix(path, ".yaml") {
-		doc, err = traverser.LoadYAML(path)
-	} else if strings.HasSuffix(path, ".json") {
-		doc, err = traverser.LoadJSON(path)
+func convert() (err error) {
+	// load the specification, converting it if it's a Swagger 2.0 document
+	doc, err := traverser.LoadSpec(cli.Convert.Doc)
+	if err != nil {
+		return err
 	}
+
+	// echo the OpenAPI 3 document to stdout as YAML
+	err = yaml.NewEncoder(os.Stdout).Encode(doc)
 	if err != nil {
-		return api, fmt.Errorf("failed loading spec: %w", err)
+		return fmt.Errorf("failed encoding document: %w", err)
 	}
 
-	// parse the OpenAPI document
-	return traverser.ParseDoc(doc)
+	return nil
 }
 
 func comment(text string) string {
@@ -173,23 +270,18 @@ func comment(text string) string {
 	)
 }
 
-func wrappedComment(lineLength int, indent string, args ...string) string {
-	desc := strings.Join(args, "")
-	desc = strings.Replace(strings.TrimSuffix(desc, "\n"), "\n", " ", -1)
-	textLength := lineLength - len(indent) - 3
-	var lines []string
-	for len(desc) > textLength {
-		lastRune := []rune(desc[:textLength])[textLength-1]
-		nextRune := []rune(desc[:textLength+1])[textLength]
-		if !unicode.IsSpace(lastRune) && !unicode.IsSpace(nextRune) {
-			// we're gonna cut the text off mid-word
-			desc = fmt.Sprintf("%s-%s", desc[:textLength-1], desc[textLength-1:])
-		}
-		lines = append(lines, fmt.Sprintf("%s// %s", indent, desc[:textLength]))
-		desc = desc[textLength:]
-	}
-	if len(desc) > 0 {
-		lines = append(lines, fmt.Sprintf("%s// %s", indent, desc))
+// number formats a numeric value as the shortest Go literal representing it
+func number(val interface{}) string {
+	switch v := val.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'g', -1, 32)
+	default:
+		return fmt.Sprint(val)
 	}
-	return strings.Join(lines, "\n")
+}
+
+func wrappedComment(lineLength int, indent string, args ...string) string {
+	return traverser.CommentDesc(indent, strings.Join(args, ""), lineLength)
 }
@@ -0,0 +1,150 @@
+package runtime
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// APIGatewayProxyRequest is the part of an API Gateway proxy integration event
+// holding the request's parameters, compatible with the event of the AWS
+// Lambda Go library
+type APIGatewayProxyRequest struct {
+	Resource                        string              `json:"resource"`
+	Path                            string              `json:"path"`
+	HTTPMethod                      string              `json:"httpMethod"`
+	Headers                         map[string]string   `json:"headers"`
+	MultiValueHeaders               map[string][]string `json:"multiValueHeaders"`
+	QueryStringParameters           map[string]string   `json:"queryStringParameters"`
+	MultiValueQueryStringParameters map[string][]string `json:"multiValueQueryStringParameters"`
+	PathParameters                  map[string]string   `json:"pathParameters"`
+	Body                            string              `json:"body"`
+	IsBase64Encoded                 bool                `json:"isBase64Encoded"`
+}
+
+// BindError aggregates the errors of all parameters that couldn't be bound
+type BindError struct {
+	Errors []error
+}
+
+func (err *BindError) Error() string {
+	msgs := make([]string, len(err.Errors))
+	for i, e := range err.Errors {
+		msgs[i] = e.Error()
+	}
+
+	return "failed binding parameters: " + strings.Join(msgs, "; ")
+}
+
+// params holds the parameters of a request by location
+type params struct {
+	path    map[string]string
+	query   url.Values
+	header  http.Header
+	cookies []*http.Cookie
+}
+
+// BindRequest binds the path, query, header and cookie parameters of an HTTP
+// request into the fields of input, which must be a pointer to a struct, as
+// described by their lambda tags. Path parameters are provided by the router
+// that matched the request. Parameters missing from the request are left
+// untouched, and all conversion errors are returned as a *BindError.
+func BindRequest(r *http.Request, pathParams map[string]string, input interface{}) error {
+	return bind(params{
+		path:    pathParams,
+		query:   r.URL.Query(),
+		header:  r.Header,
+		cookies: r.Cookies(),
+	}, input)
+}
+
+// BindEvent binds the parameters of an API Gateway proxy event into the
+// fields of input, the same way BindRequest does for HTTP requests
+func BindEvent(event APIGatewayProxyRequest, input interface{}) error {
+	query := make(url.Values)
+	for name, values := range event.MultiValueQueryStringParameters {
+		query[name] = values
+	}
+	for name, value := range event.QueryStringParameters {
+		if _, ok := query[name]; !ok {
+			query.Set(name, value)
+		}
+	}
+
+	header := make(http.Header)
+	for name, values := range event.MultiValueHeaders {
+		for _, value := range values {
+			header.Add(name, value)
+		}
+	}
+	for name, value := range event.Headers {
+		if _, ok := header[http.CanonicalHeaderKey(name)]; !ok {
+			header.Set(name, value)
+		}
+	}
+
+	// cookies are only provided through the Cookie header
+	r := http.Request{Header: header}
+
+	return bind(params{
+		path:    event.PathParameters,
+		query:   query,
+		header:  header,
+		cookies: r.Cookies(),
+	}, input)
+}
+
+func bind(source params, input interface{}) error {
+	v := reflect.ValueOf(input)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("input must be a pointer to a struct, got %T", input)
+	}
+	v = v.Elem()
+
+	var errs []error
+	for i := 0; i < v.NumField(); i++ {
+		param, ok := FieldParam(v.Type().Field(i))
+		if !ok {
+			continue
+		}
+
+		target := v.Field(i).Addr().Interface()
+		if err := source.bind(param, target); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return &BindError{Errors: errs}
+	}
+
+	return nil
+}
+
+func (source params) bind(param Param, target interface{}) error {
+	switch param.In {
+	case "path":
+		if raw, ok := source.path[param.Name]; ok {
+			return param.Decode(raw, target)
+		}
+	case "query":
+		_, err := param.DecodeQuery(source.query, target)
+		return err
+	case "header":
+		if values, ok := source.header[http.CanonicalHeaderKey(param.Name)]; ok {
+			return param.Decode(strings.Join(values, ","), target)
+		}
+	case "cookie":
+		for _, cookie := range source.cookies {
+			if cookie.Name == param.Name {
+				return param.Decode(cookie.Value, target)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported location %q of parameter %s", param.In, param.Name)
+	}
+
+	return nil
+}
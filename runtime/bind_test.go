@@ -0,0 +1,67 @@
+package runtime
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jgroeneveld/trial/assert"
+)
+
+type listPetsInput struct {
+	OwnerID   int64            `json:"-" lambda:"path.ownerID"`
+	Limit     *int64           `json:"limit,omitempty" lambda:"query.limit"`
+	Tags      []string         `json:"tags,omitempty" lambda:"query.tags" style:"form"`
+	Filter    map[string]int64 `json:"filter,omitempty" lambda:"query.filter" style:"deepObject,explode"`
+	RequestID string           `json:"-" lambda:"header.X-Request-ID"`
+	Session   string           `json:"-" lambda:"cookie.session"`
+	Body      string           `json:"body"`
+}
+
+func TestBind(t *testing.T) {
+	r := httptest.NewRequest("GET", "/owners/5/pets?limit=10&tags=cat,dog&filter[age]=3", nil)
+	r.Header.Set("X-Request-ID", "abc")
+	r.Header.Set("Cookie", "session=s3cr3t")
+
+	var input listPetsInput
+	err := BindRequest(r, map[string]string{"ownerID": "5"}, &input)
+	assert.MustBeNil(t, err, "request must be bound")
+	assert.Equal(t, int64(5), input.OwnerID)
+	assert.Equal(t, int64(10), *input.Limit)
+	assert.DeepEqual(t, []string{"cat", "dog"}, input.Tags)
+	assert.DeepEqual(t, map[string]int64{"age": 3}, input.Filter)
+	assert.Equal(t, "abc", input.RequestID)
+	assert.Equal(t, "s3cr3t", input.Session)
+
+	var fromEvent listPetsInput
+	err = BindEvent(APIGatewayProxyRequest{
+		PathParameters:        map[string]string{"ownerID": "5"},
+		QueryStringParameters: map[string]string{"limit": "10"},
+		MultiValueQueryStringParameters: map[string][]string{
+			"tags": {"cat,dog"},
+		},
+		Headers: map[string]string{"x-request-id": "abc", "cookie": "session=s3cr3t"},
+	}, &fromEvent)
+	assert.MustBeNil(t, err, "event must be bound")
+	assert.Equal(t, int64(5), fromEvent.OwnerID)
+	assert.Equal(t, int64(10), *fromEvent.Limit)
+	assert.DeepEqual(t, []string{"cat", "dog"}, fromEvent.Tags)
+	assert.Equal(t, "abc", fromEvent.RequestID, "headers must be case insensitive")
+	assert.Equal(t, "s3cr3t", fromEvent.Session)
+
+	var absent listPetsInput
+	err = BindRequest(httptest.NewRequest("GET", "/owners/5/pets", nil), map[string]string{"ownerID": "5"}, &absent)
+	assert.MustBeNil(t, err, "request must be bound")
+	assert.True(t, absent.Limit == nil, "absent optional parameters must stay nil")
+
+	var absentEvent listPetsInput
+	err = BindEvent(APIGatewayProxyRequest{PathParameters: map[string]string{"ownerID": "5"}}, &absentEvent)
+	assert.MustBeNil(t, err, "event must be bound")
+	assert.True(t, absentEvent.Limit == nil, "absent optional parameters must stay nil")
+
+	r = httptest.NewRequest("GET", "/owners/x/pets?limit=ten", nil)
+	err = BindRequest(r, map[string]string{"ownerID": "x"}, &input)
+	var bindErr *BindError
+	assert.MustBeTrue(t, errors.As(err, &bindErr), "binding errors must be aggregated")
+	assert.Equal(t, 2, len(bindErr.Errors))
+}
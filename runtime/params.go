@@ -0,0 +1,783 @@
+// Package runtime serializes and parses the parameters of the input structs
+// generated from an OpenAPI specification, as described by their struct tags.
+package runtime
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Parameter serialization styles
+const (
+	StyleMatrix         = "matrix"
+	StyleLabel          = "label"
+	StyleForm           = "form"
+	StyleSimple         = "simple"
+	StyleSpaceDelimited = "spaceDelimited"
+	StylePipeDelimited  = "pipeDelimited"
+	StyleDeepObject     = "deepObject"
+)
+
+// Param describes where a parameter is located and how it is serialized
+type Param struct {
+	Name          string
+	In            string
+	Style         string
+	Explode       bool
+	AllowReserved bool
+	ContentType   string
+}
+
+// NewParam returns a parameter serialized with the default style of its
+// location
+func NewParam(in, name string) Param {
+	param := Param{
+		Name:  name,
+		In:    in,
+		Style: DefaultStyle(in),
+	}
+	param.Explode = param.Style == StyleForm
+
+	return param
+}
+
+// DefaultStyle returns the serialization style of parameters in a location
+func DefaultStyle(in string) string {
+	switch in {
+	case "query", "cookie":
+		return StyleForm
+	case "path", "header":
+		return StyleSimple
+	default:
+		return ""
+	}
+}
+
+// FieldParam returns the parameter described by the lambda, style and content
+// tags of a struct field, and false if the field isn't a parameter
+func FieldParam(field reflect.StructField) (Param, bool) {
+	tag, ok := field.Tag.Lookup("lambda")
+	if !ok {
+		return Param{}, false
+	}
+
+	loc := strings.SplitN(tag, ".", 2)
+	if len(loc) != 2 {
+		return Param{}, false
+	}
+
+	param := NewParam(loc[0], loc[1])
+	if style, ok := field.Tag.Lookup("style"); ok {
+		opts := strings.Split(style, ",")
+		param.Style, param.Explode = opts[0], false
+		for _, opt := range opts[1:] {
+			switch opt {
+			case "explode":
+				param.Explode = true
+			case "allowReserved":
+				param.AllowReserved = true
+			}
+		}
+	}
+	param.ContentType = field.Tag.Get("content")
+
+	return param, true
+}
+
+// Encode serializes the value of a parameter. Path parameters are returned as
+// the text replacing their template expression, query and cookie parameters
+// as name=value pairs and header parameters as the header's value. Values
+// are escaped as required by their location, and nil values are encoded as
+// an empty string.
+func (p Param) Encode(value interface{}) (string, error) {
+	v := reflect.ValueOf(value)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() {
+		return "", nil
+	}
+
+	if p.ContentType != "" {
+		data, err := p.marshalContent(v.Interface())
+		if err != nil {
+			return "", err
+		}
+		return p.named(p.escape(string(data))), nil
+	}
+
+	var err error
+	var tokens []string
+	shape := shapeOf(v.Type())
+	switch shape {
+	case array:
+		for i := 0; i < v.Len(); i++ {
+			item, err := formatValue(v.Index(i))
+			if err != nil {
+				return "", p.errorf(err)
+			}
+			tokens = append(tokens, p.escape(item))
+		}
+	case object:
+		tokens, err = objectPairs(v)
+		if err != nil {
+			return "", p.errorf(err)
+		}
+		for i := range tokens {
+			tokens[i] = p.escape(tokens[i])
+		}
+	default:
+		s, err := formatValue(v)
+		if err != nil {
+			return "", p.errorf(err)
+		}
+		tokens = []string{p.escape(s)}
+	}
+
+	switch p.Style {
+	case StyleMatrix:
+		return p.encodeMatrix(shape, tokens), nil
+	case StyleLabel:
+		return p.encodeLabel(shape, tokens), nil
+	case StyleSimple:
+		if shape == object && p.Explode {
+			return strings.Join(keyValues(tokens, "="), ","), nil
+		}
+		return strings.Join(tokens, ","), nil
+	case StyleForm:
+		return p.encodeDelimited(shape, tokens, ","), nil
+	case StyleSpaceDelimited:
+		return p.encodeDelimited(shape, tokens, "%20"), nil
+	case StylePipeDelimited:
+		return p.encodeDelimited(shape, tokens, "|"), nil
+	case StyleDeepObject:
+		if shape != object {
+			return "", p.errorf(errors.New("deepObject style requires an object"))
+		}
+		pairs := make([]string, 0, len(tokens)/2)
+		for i := 0; i < len(tokens); i += 2 {
+			pairs = append(pairs, fmt.Sprintf("%s[%s]=%s", p.escape(p.Name), tokens[i], tokens[i+1]))
+		}
+		return strings.Join(pairs, "&"), nil
+	default:
+		return "", p.errorf(fmt.Errorf("unsupported style %q", p.Style))
+	}
+}
+
+func (p Param) encodeMatrix(shape valueShape, tokens []string) string {
+	name := ";" + p.escape(p.Name) + "="
+	switch {
+	case shape == array && p.Explode:
+		return name + strings.Join(tokens, name)
+	case shape == object && p.Explode:
+		return ";" + strings.Join(keyValues(tokens, "="), ";")
+	default:
+		return name + strings.Join(tokens, ",")
+	}
+}
+
+func (p Param) encodeLabel(shape valueShape, tokens []string) string {
+	switch {
+	case shape == array && p.Explode:
+		return "." + strings.Join(tokens, ".")
+	case shape == object && p.Explode:
+		return "." + strings.Join(keyValues(tokens, "="), ".")
+	default:
+		return "." + strings.Join(tokens, ",")
+	}
+}
+
+// encodeDelimited encodes form, spaceDelimited and pipeDelimited parameters,
+// which only differ by the delimiter of values that aren't exploded
+func (p Param) encodeDelimited(shape valueShape, tokens []string, delim string) string {
+	switch {
+	case shape == array && p.Explode:
+		pairs := make([]string, len(tokens))
+		for i, token := range tokens {
+			pairs[i] = p.named(token)
+		}
+		return strings.Join(pairs, "&")
+	case shape == object && p.Explode:
+		return strings.Join(keyValues(tokens, "="), "&")
+	default:
+		return p.named(strings.Join(tokens, delim))
+	}
+}
+
+// named prefixes a value with the parameter's name where its location
+// requires it
+func (p Param) named(value string) string {
+	if p.In == "query" || p.In == "cookie" {
+		return p.escape(p.Name) + "=" + value
+	}
+
+	return value
+}
+
+// reservedReplacer restores the reserved characters of a query escaped value
+var reservedReplacer = strings.NewReplacer(
+	"%3A", ":", "%2F", "/", "%3F", "?", "%23", "#", "%5B", "[", "%5D", "]",
+	"%40", "@", "%21", "!", "%24", "$", "%26", "&", "%27", "'", "%28", "(",
+	"%29", ")", "%2A", "*", "%2B", "+", "%2C", ",", "%3B", ";", "%3D", "=",
+)
+
+func (p Param) escape(s string) string {
+	switch p.In {
+	case "path":
+		return url.PathEscape(s)
+	case "query":
+		escaped := strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+		if p.AllowReserved {
+			return reservedReplacer.Replace(escaped)
+		}
+		return escaped
+	default:
+		return s
+	}
+}
+
+// Decode parses the value of a path, header or cookie parameter into target,
+// which must be a pointer. Values are expected as provided by routers and
+// API Gateway, i.e. already unescaped, and cookie values without the
+// cookie's name.
+func (p Param) Decode(raw string, target interface{}) error {
+	v, err := targetValue(target)
+	if err != nil {
+		return p.errorf(err)
+	}
+
+	if p.ContentType != "" {
+		return p.unmarshalContent(raw, target)
+	}
+
+	shape := shapeOf(v.Type())
+	var tokens []string
+	switch p.Style {
+	case StyleMatrix:
+		tokens, err = p.splitMatrix(shape, raw)
+		if err != nil {
+			return p.errorf(err)
+		}
+	case StyleLabel:
+		raw = strings.TrimPrefix(raw, ".")
+		delim := ","
+		if p.Explode && shape != primitive {
+			delim = "."
+		}
+		tokens = splitExploded(shape, p.Explode, raw, delim)
+	case StyleSimple, StyleForm:
+		tokens = splitExploded(shape, p.Explode, raw, ",")
+	default:
+		return p.errorf(fmt.Errorf("unsupported style %q for %s parameters", p.Style, p.In))
+	}
+
+	return p.assign(v, shape, tokens)
+}
+
+func (p Param) splitMatrix(shape valueShape, raw string) ([]string, error) {
+	segments := strings.Split(strings.TrimPrefix(raw, ";"), ";")
+	if shape == object && p.Explode {
+		return splitPairs(segments), nil
+	}
+
+	var tokens []string
+	for _, segment := range segments {
+		parts := strings.SplitN(segment, "=", 2)
+		if len(parts) != 2 || parts[0] != p.Name {
+			return nil, fmt.Errorf("expected %q, got %q", ";"+p.Name+"=", raw)
+		}
+		if shape == primitive || (shape == array && p.Explode) {
+			tokens = append(tokens, parts[1])
+		} else {
+			tokens = append(tokens, strings.Split(parts[1], ",")...)
+		}
+	}
+
+	return tokens, nil
+}
+
+// DecodeQuery parses the value of a query parameter into target, which must
+// be a pointer. It returns false if the parameter isn't present in the query.
+// Exploded form objects that aren't structs collect every query parameter.
+func (p Param) DecodeQuery(query url.Values, target interface{}) (bool, error) {
+	typ, err := targetType(target)
+	if err != nil {
+		return false, p.errorf(err)
+	}
+
+	shape := shapeOf(typ)
+	if p.ContentType != "" {
+		values, ok := query[p.Name]
+		if !ok {
+			return false, nil
+		}
+		return true, p.unmarshalContent(values[0], target)
+	}
+
+	var tokens []string
+	switch {
+	case p.Style == StyleDeepObject:
+		prefix := p.Name + "["
+		for _, key := range sortedKeys(query) {
+			if strings.HasPrefix(key, prefix) && strings.HasSuffix(key, "]") {
+				tokens = append(tokens, key[len(prefix):len(key)-1], query.Get(key))
+			}
+		}
+		if tokens == nil {
+			return false, nil
+		}
+	case p.Explode && shape == array:
+		values, ok := query[p.Name]
+		if !ok {
+			return false, nil
+		}
+		tokens = values
+	case p.Explode && shape == object:
+		for _, key := range objectKeys(typ, query) {
+			if values, ok := query[key]; ok {
+				tokens = append(tokens, key, values[0])
+			}
+		}
+		if tokens == nil {
+			return false, nil
+		}
+	default:
+		values, ok := query[p.Name]
+		if !ok {
+			return false, nil
+		}
+		delim := ","
+		switch p.Style {
+		case StyleSpaceDelimited:
+			delim = " "
+		case StylePipeDelimited:
+			delim = "|"
+		case StyleForm:
+		default:
+			return true, p.errorf(fmt.Errorf("unsupported style %q for query parameters", p.Style))
+		}
+		if shape == primitive {
+			tokens = values[:1]
+		} else {
+			tokens = strings.Split(values[0], delim)
+		}
+	}
+
+	// pointers are only allocated once the parameter is known to be present
+	v, err := targetValue(target)
+	if err != nil {
+		return true, p.errorf(err)
+	}
+
+	return true, p.assign(v, shape, tokens)
+}
+
+func (p Param) assign(v reflect.Value, shape valueShape, tokens []string) error {
+	var err error
+	switch shape {
+	case array:
+		err = assignArray(v, tokens)
+	case object:
+		err = assignObject(v, tokens)
+	default:
+		if len(tokens) == 0 {
+			tokens = []string{""}
+		}
+		err = assignValue(v, tokens[0])
+	}
+	if err != nil {
+		return p.errorf(err)
+	}
+
+	return nil
+}
+
+func (p Param) marshalContent(value interface{}) ([]byte, error) {
+	if !isJSON(p.ContentType) {
+		return nil, p.errorf(fmt.Errorf("unsupported content type %s", p.ContentType))
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, p.errorf(err)
+	}
+
+	return data, nil
+}
+
+func (p Param) unmarshalContent(raw string, target interface{}) error {
+	if !isJSON(p.ContentType) {
+		return p.errorf(fmt.Errorf("unsupported content type %s", p.ContentType))
+	}
+
+	err := json.Unmarshal([]byte(raw), target)
+	if err != nil {
+		return p.errorf(err)
+	}
+
+	return nil
+}
+
+func (p Param) errorf(err error) error {
+	return fmt.Errorf("invalid %s parameter %s: %w", p.In, p.Name, err)
+}
+
+func isJSON(contentType string) bool {
+	return contentType == "application/json" || strings.HasSuffix(contentType, "+json")
+}
+
+// valueShape is how a value is serialized: as a single primitive, as an array
+// of primitives or as an object of primitive properties
+type valueShape int
+
+const (
+	primitive valueShape = iota
+	array
+	object
+)
+
+var (
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func shapeOf(t reflect.Type) valueShape {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	if t.Implements(textMarshaler) || reflect.PtrTo(t).Implements(textUnmarshaler) {
+		return primitive
+	}
+
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return primitive
+		}
+		return array
+	case reflect.Map, reflect.Struct:
+		return object
+	default:
+		return primitive
+	}
+}
+
+// targetType returns the type of the value a target points to, through any
+// nested pointers, without allocating them
+func targetType(target interface{}) (reflect.Type, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil, fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+
+	t := v.Type().Elem()
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	return t, nil
+}
+
+func targetValue(target interface{}) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return v, fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+
+	v = v.Elem()
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	return v, nil
+}
+
+func formatValue(v reflect.Value) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if m, ok := v.Interface().(encoding.TextMarshaler); ok {
+		text, err := m.MarshalText()
+		return string(text), err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()), nil
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return base64.StdEncoding.EncodeToString(v.Bytes()), nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+func assignValue(v reflect.Value, s string) error {
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.CanAddr() {
+		if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("expected a boolean, got %q", s)
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected an integer, got %q", s)
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a non-negative integer, got %q", s)
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", s)
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() != reflect.Uint8 {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		data, err := base64.StdEncoding.DecodeString(s)
+		if err != nil {
+			return fmt.Errorf("expected base64 encoded data, got %q", s)
+		}
+		v.SetBytes(data)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+func assignArray(v reflect.Value, items []string) error {
+	if v.Kind() != reflect.Slice {
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+	for i, item := range items {
+		if err := assignValue(slice.Index(i), item); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	v.Set(slice)
+
+	return nil
+}
+
+func assignObject(v reflect.Value, pairs []string) error {
+	if len(pairs)%2 != 0 {
+		return fmt.Errorf("expected key and value pairs, got %q", strings.Join(pairs, ","))
+	}
+
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", v.Type())
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for i := 0; i < len(pairs); i += 2 {
+			val := reflect.New(v.Type().Elem()).Elem()
+			if err := assignValue(val, pairs[i+1]); err != nil {
+				return fmt.Errorf("property %s: %w", pairs[i], err)
+			}
+			v.SetMapIndex(reflect.ValueOf(pairs[i]).Convert(v.Type().Key()), val)
+		}
+	case reflect.Struct:
+		fields := structFields(v.Type())
+		for i := 0; i < len(pairs); i += 2 {
+			index, ok := fields[pairs[i]]
+			if !ok {
+				continue
+			}
+			if err := assignValue(v.Field(index), pairs[i+1]); err != nil {
+				return fmt.Errorf("property %s: %w", pairs[i], err)
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+
+	return nil
+}
+
+// objectPairs returns the properties of a map or struct as a flat list of
+// keys and values, maps being sorted by key and structs in field order
+func objectPairs(v reflect.Value) ([]string, error) {
+	var pairs []string
+	switch v.Kind() {
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return keys[i].String() < keys[j].String()
+		})
+		for _, key := range keys {
+			val, err := formatValue(v.MapIndex(key))
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", key, err)
+			}
+			pairs = append(pairs, key.String(), val)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			field := v.Field(i)
+			if field.Kind() == reflect.Ptr && field.IsNil() {
+				continue
+			}
+			val, err := formatValue(field)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %w", name, err)
+			}
+			pairs = append(pairs, name, val)
+		}
+	}
+
+	return pairs, nil
+}
+
+// objectKeys returns the query parameters holding the properties of an
+// exploded object: a struct's fields, or every parameter for maps
+func objectKeys(t reflect.Type, query url.Values) []string {
+	if t.Kind() != reflect.Struct {
+		return sortedKeys(query)
+	}
+
+	var keys []string
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := fieldName(t.Field(i)); ok {
+			keys = append(keys, name)
+		}
+	}
+
+	return keys
+}
+
+func structFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		if name, ok := fieldName(t.Field(i)); ok {
+			fields[name] = i
+		}
+	}
+
+	return fields
+}
+
+// fieldName returns the name of a struct field's property, as serialized to
+// JSON
+func fieldName(field reflect.StructField) (string, bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+
+	name := strings.Split(field.Tag.Get("json"), ",")[0]
+	switch name {
+	case "-":
+		return "", false
+	case "":
+		return field.Name, true
+	default:
+		return name, true
+	}
+}
+
+func sortedKeys(query url.Values) []string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// splitExploded splits a value that is delimited the same way whether it's
+// exploded or not, except for exploded objects which hold key=value pairs
+func splitExploded(shape valueShape, explode bool, raw, delim string) []string {
+	switch {
+	case shape == primitive:
+		return []string{raw}
+	case raw == "":
+		return nil
+	case shape == object && explode:
+		return splitPairs(strings.Split(raw, delim))
+	default:
+		return strings.Split(raw, delim)
+	}
+}
+
+func splitPairs(segments []string) []string {
+	var pairs []string
+	for _, segment := range segments {
+		parts := strings.SplitN(segment, "=", 2)
+		if len(parts) == 1 {
+			parts = append(parts, "")
+		}
+		pairs = append(pairs, parts...)
+	}
+
+	return pairs
+}
+
+func keyValues(pairs []string, sep string) []string {
+	kvs := make([]string, 0, len(pairs)/2)
+	for i := 0; i+1 < len(pairs); i += 2 {
+		kvs = append(kvs, pairs[i]+sep+pairs[i+1])
+	}
+
+	return kvs
+}
@@ -0,0 +1,85 @@
+package runtime
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/jgroeneveld/trial/assert"
+)
+
+type color struct {
+	R int64 `json:"R"`
+	G int64 `json:"G"`
+	B int64 `json:"B"`
+}
+
+func TestParamStyles(t *testing.T) {
+	tests := []struct {
+		param   Param
+		value   interface{}
+		encoded string
+	}{
+		{Param{Name: "color", In: "path", Style: StyleSimple}, []string{"blue", "black", "brown"}, "blue,black,brown"},
+		{Param{Name: "color", In: "path", Style: StyleSimple, Explode: true}, color{100, 200, 150}, "R=100,G=200,B=150"},
+		{Param{Name: "color", In: "path", Style: StyleLabel}, "blue", ".blue"},
+		{Param{Name: "color", In: "path", Style: StyleLabel, Explode: true}, []string{"blue", "black"}, ".blue.black"},
+		{Param{Name: "color", In: "path", Style: StyleMatrix}, color{100, 200, 150}, ";color=R,100,G,200,B,150"},
+		{Param{Name: "color", In: "path", Style: StyleMatrix, Explode: true}, []string{"blue", "black"}, ";color=blue;color=black"},
+		{Param{Name: "color", In: "query", Style: StyleForm}, []string{"blue", "black"}, "color=blue,black"},
+		{Param{Name: "color", In: "query", Style: StyleForm, Explode: true}, []int64{1, 2}, "color=1&color=2"},
+		{Param{Name: "color", In: "query", Style: StyleForm, Explode: true}, color{100, 200, 150}, "R=100&G=200&B=150"},
+		{Param{Name: "color", In: "query", Style: StyleSpaceDelimited}, []string{"blue", "black"}, "color=blue%20black"},
+		{Param{Name: "color", In: "query", Style: StylePipeDelimited}, []string{"blue", "black"}, "color=blue|black"},
+		{Param{Name: "color", In: "query", Style: StyleDeepObject, Explode: true}, map[string]int64{"R": 100, "G": 200}, "color[G]=200&color[R]=100"},
+		{Param{Name: "q", In: "query", Style: StyleForm, Explode: true}, "a/b c", "q=a%2Fb%20c"},
+		{Param{Name: "q", In: "query", Style: StyleForm, Explode: true, AllowReserved: true}, "a/b c", "q=a/b%20c"},
+		{Param{Name: "filter", In: "query", ContentType: "application/json"}, color{1, 2, 3}, "filter=%7B%22R%22%3A1%2C%22G%22%3A2%2C%22B%22%3A3%7D"},
+	}
+
+	for _, test := range tests {
+		encoded, err := test.param.Encode(test.value)
+		assert.MustBeNil(t, err, "value must be encoded")
+		assert.Equal(t, test.encoded, encoded, "%s value must be encoded", test.param.Style)
+	}
+
+	var colors []string
+	err := Param{Name: "color", In: "path", Style: StyleMatrix, Explode: true}.
+		Decode(";color=blue;color=black", &colors)
+	assert.MustBeNil(t, err, "matrix array must be decoded")
+	assert.DeepEqual(t, []string{"blue", "black"}, colors, "matrix array must be decoded")
+
+	var rgb color
+	err = Param{Name: "color", In: "header", Style: StyleSimple, Explode: true}.
+		Decode("R=100,G=200,B=150", &rgb)
+	assert.MustBeNil(t, err, "simple object must be decoded")
+	assert.Equal(t, color{100, 200, 150}, rgb, "simple object must be decoded")
+
+	query, _ := url.ParseQuery("ids=1|2|3&color[R]=100&color[B]=150&limit=10")
+
+	var ids []int64
+	found, err := Param{Name: "ids", In: "query", Style: StylePipeDelimited}.DecodeQuery(query, &ids)
+	assert.MustBeNil(t, err, "pipe delimited array must be decoded")
+	assert.True(t, found, "pipe delimited array must be found")
+	assert.DeepEqual(t, []int64{1, 2, 3}, ids, "pipe delimited array must be decoded")
+
+	deep := map[string]int64{}
+	found, err = Param{Name: "color", In: "query", Style: StyleDeepObject, Explode: true}.DecodeQuery(query, &deep)
+	assert.MustBeNil(t, err, "deep object must be decoded")
+	assert.True(t, found, "deep object must be found")
+	assert.DeepEqual(t, map[string]int64{"R": 100, "B": 150}, deep, "deep object must be decoded")
+
+	var limit *int64
+	found, err = NewParam("query", "limit").DecodeQuery(query, &limit)
+	assert.MustBeNil(t, err, "primitive must be decoded")
+	assert.True(t, found, "primitive must be found")
+	assert.Equal(t, int64(10), *limit, "primitive must be decoded")
+
+	var offset int64
+	found, err = NewParam("query", "offset").DecodeQuery(query, &offset)
+	assert.MustBeNil(t, err, "missing parameter must not fail")
+	assert.True(t, !found, "missing parameter must not be found")
+
+	var id int64
+	err = NewParam("path", "id").Decode("abc", &id)
+	assert.NotNil(t, err, "invalid integer must fail")
+}
@@ -1,8 +1,10 @@
 package traverser
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"os"
 
 	"gopkg.in/yaml.v2"
@@ -25,17 +27,100 @@ func LoadYAML(path string) (doc Map, err error) {
 }
 
 func LoadJSON(path string) (doc Map, err error) {
-	file, err := os.Open(path)
+	data, err := ioutil.ReadFile(path)
 	if err != nil {
 		return doc, fmt.Errorf("failed opening %q: %w", path, err)
 	}
 
-	defer file.Close() // nolint: errcheck
-
-	err = json.NewDecoder(file).Decode(&doc)
+	doc, err = decodeJSON(data)
 	if err != nil {
 		return doc, fmt.Errorf("failed parsing %q: %w", path, err)
 	}
 
 	return doc, nil
 }
+
+// LoadSpec loads an API specification, decoding it as JSON or YAML depending
+// on its content. Swagger 2.0 documents are converted to OpenAPI 3 on the fly.
+func LoadSpec(path string) (doc Map, err error) {
+	doc, _, err = loadSpec(path)
+	return doc, err
+}
+
+// loadSpec loads an API specification like LoadSpec, also returning where the
+// values of converted documents come from, or nil if it wasn't converted
+func loadSpec(path string) (doc Map, src sources, err error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return doc, nil, fmt.Errorf("failed opening %q: %w", path, err)
+	}
+
+	if isJSON(data) {
+		doc, err = decodeJSON(data)
+	} else {
+		err = yaml.Unmarshal(data, &doc)
+	}
+	if err != nil {
+		return doc, nil, fmt.Errorf("failed parsing %q: %w", path, err)
+	}
+
+	if IsSwagger2(doc) {
+		doc, src, err = convertSwagger2(doc)
+		if err != nil {
+			return doc, nil, fmt.Errorf("failed converting %q from Swagger 2.0: %w", path, err)
+		}
+	}
+
+	return doc, src, nil
+}
+
+// isJSON returns whether a document's content is JSON rather than YAML,
+// JSON being mostly, but not entirely, a subset of YAML
+func isJSON(data []byte) bool {
+	data = bytes.TrimSpace(data)
+	return len(data) > 0 && data[0] == '{'
+}
+
+// decodeJSON decodes a JSON document into the same types the YAML decoder
+// produces, so that both can be traversed the same way
+func decodeJSON(data []byte) (doc Map, err error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var val interface{}
+	err = dec.Decode(&val)
+	if err != nil {
+		return doc, err
+	}
+
+	doc, ok := fromJSON(val).(Map)
+	if !ok {
+		return doc, fmt.Errorf("document is not an object")
+	}
+
+	return doc, nil
+}
+
+func fromJSON(val interface{}) interface{} {
+	switch v := val.(type) {
+	case map[string]interface{}:
+		m := make(Map, len(v))
+		for key, item := range v {
+			m[key] = fromJSON(item)
+		}
+		return m
+	case []interface{}:
+		for i, item := range v {
+			v[i] = fromJSON(item)
+		}
+		return v
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return int(i)
+		}
+		f, _ := v.Float64()
+		return f
+	default:
+		return val
+	}
+}
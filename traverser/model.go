@@ -1,18 +1,59 @@
 package traverser
 
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
 type API struct {
-	Title       string
-	Description string
-	GoName      string
-	JsName      string
-	Version     string
-	Contact     Contact
-	Servers     []Server
-	Methods     []Method
-	Schemas     []Schema
-	Consts      []Const
-	RefDocs     map[string]API
-	Specs       []Spec
+	Title           string
+	Description     string
+	GoName          string
+	JsName          string
+	Version         string
+	OpenAPI         string
+	Contact         Contact
+	Servers         []Server
+	Methods         []Method
+	Webhooks        []Method
+	Schemas         []Schema
+	Consts          []Const
+	Tags            []Tag
+	SecuritySchemes []SecurityScheme
+	Security        []SecurityRequirement
+	RefDocs         map[string]API
+	Imports         []Import
+	Specs           []Spec
+	Warnings        []string
+}
+
+// TaggedMethods returns the methods of a tag, which lets templates group
+// methods into one interface or package per tag
+func (api API) TaggedMethods(tag string) []Method {
+	var methods []Method
+	for _, method := range api.Methods {
+		for _, t := range method.Tags {
+			if t == tag {
+				methods = append(methods, method)
+				break
+			}
+		}
+	}
+
+	return methods
+}
+
+// UntaggedMethods returns the methods that don't belong to any tag
+func (api API) UntaggedMethods() []Method {
+	var methods []Method
+	for _, method := range api.Methods {
+		if len(method.Tags) == 0 {
+			methods = append(methods, method)
+		}
+	}
+
+	return methods
 }
 
 type Spec struct {
@@ -23,6 +64,15 @@ type Spec struct {
 	JsName string
 }
 
+// Import is a package holding schemas referenced by the API, generated from a
+// document in another directory. Name is the package's name, i.e. that of the
+// directory, and Path its import path if it could be determined.
+type Import struct {
+	Name string
+	Dir  string
+	Path string
+}
+
 func (api API) GetSchema(name string) Schema {
 	for _, schema := range api.Schemas {
 		if schema.GoName == name || schema.JsName == name {
@@ -43,20 +93,136 @@ func (api API) IsConst(typeName string) bool {
 	return false
 }
 
-func (api API) FindConst(constName string, constValue interface{}) ConstValue {
+// IsSchema returns whether a type is one of the API's (object) schemas
+func (api API) IsSchema(typeName string) bool {
+	for _, schema := range api.Schemas {
+		if schema.GoName == typeName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetConst returns the enum with the provided name
+func (api API) GetConst(name string) Const {
 	for _, c := range api.Consts {
-		if c.Name == constName {
-			for _, v := range c.Values {
-				if v.APIName == constValue.(string) {
-					return v
-				}
-			}
+		if c.Name == name {
+			return c
+		}
+	}
+
+	return Const{}
+}
+
+// FindConst returns the value of an enum matching constValue, which may be of
+// any type as long as its string representation matches, e.g. 1 or "1"
+func (api API) FindConst(constName string, constValue interface{}) ConstValue {
+	for _, v := range api.GetConst(constName).Values {
+		if v.APIName == fmt.Sprint(constValue) {
+			return v
 		}
 	}
 
 	return ConstValue{}
 }
 
+// DefaultValue returns the Go expression of a parameter's default value, or an
+// empty string if it has none that its type can hold. Defaults of enums are
+// their constants, those of defined types are converted to them.
+func (api API) DefaultValue(param Param) string {
+	if param.Default == nil {
+		return ""
+	}
+
+	typeName := param.BaseGoType
+	if api.IsConst(typeName) {
+		return api.FindConst(typeName, param.Default).GoName
+	}
+
+	var conv string
+	if schema := api.GetSchema(typeName); schema.Underlying != nil {
+		conv, typeName = typeName, schema.Underlying.GoType
+	}
+
+	var literal string
+	switch def := param.Default.(type) {
+	case string:
+		switch typeName {
+		case String:
+			literal = strconv.Quote(def)
+		case "time.Time":
+			layout := time.RFC3339
+			if param.Format == Date {
+				layout = "2006-01-02"
+			}
+			t, err := time.Parse(layout, def)
+			if err != nil {
+				return ""
+			}
+			t = t.UTC()
+			literal = fmt.Sprintf("time.Date(%d, %d, %d, %d, %d, %d, %d, time.UTC)",
+				t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(),
+			)
+		default:
+			return ""
+		}
+	case bool:
+		if typeName != "bool" {
+			return ""
+		}
+		literal = strconv.FormatBool(def)
+	case int, int64, float64:
+		switch typeName {
+		case Int64, Int32, Uint64, Uint32, Uint16, Uint8, Float32, Float64:
+		default:
+			return ""
+		}
+		literal = fmt.Sprint(def)
+		if f, ok := def.(float64); ok {
+			literal = strconv.FormatFloat(f, 'g', -1, 64)
+		}
+		if conv == "" {
+			// untyped constants would default to int or float64
+			conv = typeName
+		}
+	default:
+		return ""
+	}
+
+	if conv != "" {
+		return conv + "(" + literal + ")"
+	}
+
+	return literal
+}
+
+// UsesFormat returns whether any string parameter of the API's schemas is of
+// the provided format
+func (api API) UsesFormat(format string) bool {
+	var uses func(param *Param) bool
+	uses = func(param *Param) bool {
+		if param == nil {
+			return false
+		}
+		return (param.BaseGoType == String && param.Format == format) ||
+			uses(param.Items) || uses(param.Values)
+	}
+
+	for _, schema := range api.Schemas {
+		for i := range schema.Params {
+			if uses(&schema.Params[i]) {
+				return true
+			}
+		}
+		if uses(schema.AdditionalProperties) || uses(schema.Underlying) {
+			return true
+		}
+	}
+
+	return false
+}
+
 type Contact struct {
 	Name string
 	URL  string
@@ -81,9 +247,100 @@ type Method struct {
 	OutputType       string
 	InputInBody      bool
 	SuccessfulStatus int
+	RequestContent   []MediaType
+	Responses        []Response
+	Security         []SecurityRequirement
+	Tags             []string
+	Webhook          string
 	Source           string
 }
 
+// Response describes one of a method's responses. Status is the status code
+// as written in the document, e.g. "201", "4XX" or "default", StatusCode is
+// zero for ranges and the default response.
+type Response struct {
+	Status      string
+	StatusCode  int
+	Description string
+	ContentType string
+	GoType      string
+	JsType      string
+	Headers     []Param
+	Content     []MediaType
+	IsError     bool
+}
+
+// MediaType describes the body of a request or response in one of its media
+// types
+type MediaType struct {
+	ContentType string
+	GoType      string
+	JsType      string
+	Encoding    []Encoding
+}
+
+// Encoding describes how a property of a multipart or form body is encoded
+type Encoding struct {
+	Property      string
+	ContentType   string
+	Style         string
+	Explode       bool
+	AllowReserved bool
+}
+
+// Tag groups methods, e.g. by the team or resource they belong to
+type Tag struct {
+	APIName     string
+	GoName      string
+	JsName      string
+	Description string
+}
+
+// SecurityScheme describes a way of authenticating requests. In and ParamName
+// apply to API keys, Scheme and BearerFormat to HTTP authentication, Flows to
+// OAuth2 and OpenIDConnectURL to OpenID Connect.
+type SecurityScheme struct {
+	APIName          string
+	GoName           string
+	JsName           string
+	Type             string
+	Description      string
+	In               string
+	ParamName        string
+	Scheme           string
+	BearerFormat     string
+	Flows            []OAuthFlow
+	OpenIDConnectURL string
+}
+
+type OAuthFlow struct {
+	Type             string
+	AuthorizationURL string
+	TokenURL         string
+	RefreshURL       string
+	Scopes           []Scope
+}
+
+type Scope struct {
+	Name        string
+	Description string
+}
+
+// SecurityRequirement is satisfied when all of its schemes are. A method may
+// list several alternative requirements, one with no schemes making
+// authentication optional. A method without any requirement is public.
+type SecurityRequirement struct {
+	Schemes []RequiredScheme
+}
+
+// RequiredScheme is a security scheme required by a method, along with the
+// OAuth2 or OpenID Connect scopes (or roles, for other types) it requires
+type RequiredScheme struct {
+	APIName string
+	GoName  string
+	Scopes  []string
+}
+
 type Schema struct {
 	APIName       string
 	GoName        string
@@ -95,7 +352,13 @@ type Schema struct {
 	AnyOf         []string
 	AllOf         []string
 	Discriminator Discriminator
-	Source        string
+	// AdditionalProperties holds the catch-all map of an object that mixes
+	// fixed properties with additionalProperties
+	AdditionalProperties *Param
+	// Underlying describes the type of a schema that isn't an object with
+	// properties, which is generated as a defined type rather than a struct
+	Underlying *Param
+	Source     string
 }
 
 type Discriminator struct {
@@ -116,28 +379,52 @@ func (schema Schema) GetParam(name string) Param {
 }
 
 type Param struct {
-	APIName         string
-	SpecName        string
-	GoName          string
-	JsName          string
-	GoType          string
-	JsType          string
-	IsArray         bool
-	ArrayItemGoType string
-	ArrayItemJsType string
-	Required        bool
-	AllowEmpty      bool
-	Description     string
-	Deprecated      bool
-	In              string
-	Tags            string
-	Default         interface{}
-	Minimum         interface{}
-	Maximum         interface{}
-	MinLength       interface{}
-	MaxLength       interface{}
-	ValidURL        bool
-	RequiredIf      RequiredIf
+	APIName          string
+	SpecName         string
+	GoName           string
+	JsName           string
+	GoType           string
+	BaseGoType       string
+	JsType           string
+	IsArray          bool
+	ArrayItemGoType  string
+	ArrayItemJsType  string
+	Items            *Param
+	IsMap            bool
+	Values           *Param
+	Required         bool
+	AllowEmpty       bool
+	Description      string
+	Deprecated       bool
+	Nullable         bool
+	Optional         bool
+	HasDefault       bool
+	Pointer          bool
+	In               string
+	Style            string
+	Explode          bool
+	AllowReserved    bool
+	ContentType      string
+	Tags             string
+	Default          interface{}
+	Const            interface{}
+	Examples         []interface{}
+	Minimum          interface{}
+	ExclusiveMinimum bool
+	Maximum          interface{}
+	ExclusiveMaximum bool
+	MultipleOf       interface{}
+	MinLength        interface{}
+	MaxLength        interface{}
+	Pattern          string
+	Format           string
+	MinItems         interface{}
+	MaxItems         interface{}
+	UniqueItems      bool
+	MinProperties    interface{}
+	MaxProperties    interface{}
+	ValidURL         bool
+	RequiredIf       RequiredIf
 }
 
 type RequiredIf struct {
@@ -147,12 +434,14 @@ type RequiredIf struct {
 
 type Const struct {
 	Name   string
+	Type   string
 	Values []ConstValue
 	Source string
 }
 
 type ConstValue struct {
 	APIName     string
+	Value       interface{}
 	GoName      string
 	JsName      string
 	Description string
@@ -174,6 +463,8 @@ const (
 	Uint8    = "uint8"
 	Number   = "number"
 	Float    = "float"
+	Float32  = "float32"
+	Float64  = "float64"
 	Boolean  = "boolean"
 	Double   = "double"
 	Array    = "array"
@@ -2,39 +2,105 @@ package traverser
 
 import (
 	"fmt"
+	"math"
 	"path/filepath"
+	"reflect"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"unicode"
 )
 
 type parser struct {
 	api    *API
 	doc    Map
 	consts map[string]Const
+	// enumOwners lists the schemas sharing each inline enum
+	enumOwners map[string][]string
+	// enumPointers locates the first declaration of each enum
+	enumPointers map[string]string
+	// path is the absolute path of the document, if it was loaded from a file
+	path      string
+	resolver  *Resolver
+	positions positions
+	// root is the API of the root document, whose security schemes and
+	// requirements apply to the operations of the documents it references
+	root *API
 }
 
+// ParseDoc parses an API specification, resolving the external references it
+// contains relative to the working directory
 func ParseDoc(doc Map) (api API, err error) {
+	api, err = parseDoc(doc, "", NewResolver(), nil)
+	return api, locate(err)
+}
+
+// ParseFile loads and parses the API specification at path, resolving the
+// external references it contains relative to its location
+func ParseFile(path string, resolver *Resolver) (api API, err error) {
+	api, err = parseFile(path, resolver, nil)
+	return api, locate(err)
+}
+
+// parseFile loads and parses the document at path, referenced by the document
+// of the root API unless root is nil. Only the root document has to be a
+// specification, the documents it references may hold nothing but components
+// or path items.
+func parseFile(path string, resolver *Resolver, root *API) (api API, err error) {
+	doc, abs, err := resolver.Load(path)
+	if err != nil {
+		return api, fmt.Errorf("failed loading spec: %w", err)
+	}
+
+	resolver.parsing[abs] = true
+	defer delete(resolver.parsing, abs)
+
+	return parseDoc(doc, abs, resolver, root)
+}
+
+func parseDoc(doc Map, path string, resolver *Resolver, root *API) (api API, err error) {
 	api.Title = doc.Str("info", "title")
 	api.Description = doc.Str("info", "description")
 	api.GoName = fmt.Sprintf("%s", strings.Replace(api.Title, " ", "", -1))
 	api.JsName = fmt.Sprintf("%s", strings.ToLower(api.GoName))
 	api.Version = doc.Str("info", "version")
+	api.OpenAPI = doc.Str("openapi")
 	api.Contact.Name = doc.Str("info", "contact", "name")
 	api.Contact.URL = doc.Str("info", "contact", "url")
 	api.RefDocs = make(map[string]API)
 
 	p := &parser{
-		api:    &api,
-		doc:    doc,
-		consts: make(map[string]Const),
+		api:          &api,
+		doc:          doc,
+		path:         path,
+		resolver:     resolver,
+		positions:    resolver.index[path],
+		consts:       make(map[string]Const),
+		enumOwners:   make(map[string][]string),
+		enumPointers: make(map[string]string),
+		root:         root,
+	}
+	if root == nil {
+		p.root = p.api
+	}
+
+	// a document that isn't a specification would just generate nothing
+	if root == nil && api.OpenAPI == "" {
+		return api, p.errorAt("", fmt.Errorf("not an OpenAPI 3 document, the openapi field is missing"))
 	}
 
 	for _, fn := range []func() error{
+		p.inlineRefs,
+		p.checkPatterns,
 		p.parseServers,
+		p.parseSecuritySchemes,
+		p.parseExternalRefs,
 		p.parseSchemas,
+		p.breakCycles,
 		p.parsePaths,
+		p.parseWebhooks,
+		p.parseTags,
 		p.parseEnums,
 	} {
 		err = fn()
@@ -60,289 +126,1213 @@ func (p *parser) parseServers() error {
 	return nil
 }
 
-func (p *parser) parseSchemas() error {
-	for _, name := range p.doc.Keys("components", "schemas") {
-		s, _ := p.doc.Get("components", "schemas", name)
-		switch s.Str("type") {
-		case Object:
-			p.parseSchema(name, s)
-		case String:
-			p.parseConst(name, s)
+// inlineRefs replaces the references pointing within other objects, e.g. to
+// a property of a schema, by their target, so that only references to
+// reusable objects are left for the rest of the parser to follow. Recursive
+// references are left as they are.
+func (p *parser) inlineRefs() error {
+	_, err := p.inline(p.doc, "", nil)
+	return err
+}
+
+// inline replaces the references of a value found at a JSON pointer of the
+// document, following a chain of references
+func (p *parser) inline(val interface{}, pointer string, chain []string) (interface{}, error) {
+	switch v := val.(type) {
+	case Map:
+		if ref, ok := v["$ref"].(string); ok {
+			refPointer := pointer + "/$ref"
+			_, fragment, err := ResolveRef(p.path, ref)
+			if err != nil {
+				return v, p.errorAt(refPointer, err)
+			}
+			tokens, err := ParsePointer(fragment)
+			if err != nil {
+				return v, p.errorAt(refPointer, fmt.Errorf("invalid reference %q: %w", ref, err))
+			}
+			if hasString(chain, ref) {
+				return v, nil
+			}
+
+			target, err := p.lookup(ref)
+			if err != nil {
+				return v, p.errorAt(refPointer, err)
+			}
+			if isNamedRef(tokens) {
+				return v, nil
+			}
+
+			return p.inline(target.data, pointer, append(chain, ref))
+		}
+
+		for key, item := range v {
+			inlined, err := p.inline(item, pointer+"/"+escapePointer(keyString(key)), chain)
+			if err != nil {
+				return v, err
+			}
+			v[key] = inlined
+		}
+	case []interface{}:
+		for i, item := range v {
+			inlined, err := p.inline(item, fmt.Sprintf("%s/%d", pointer, i), chain)
+			if err != nil {
+				return v, err
+			}
+			v[i] = inlined
 		}
 	}
 
-	return nil
+	return val, nil
 }
 
-func (p *parser) parseSchema(name string, s Any) {
-	required := make(map[string]bool)
-	for _, param := range s.Slice("required") {
-		required[param.Str()] = true
+// checkPatterns rejects the patterns Go's regular expressions don't support,
+// such as lookarounds and backreferences, which generated code could not
+// compile. Only the schemas of the document are checked, examples and
+// extensions may hold anything.
+func (p *parser) checkPatterns() error {
+	type schemaAt struct {
+		schema  Any
+		pointer string
 	}
+	var schemas []schemaAt
 
-	schema := Schema{
-		APIName:     name,
-		GoName:      name,
-		JsName:      name,
-		Description: s.Str("description"),
-		ErrorFormat: s.Str("x-go-error"),
+	// parameters and headers have a schema or content, bodies only content
+	addParam := func(param Any, pointer string) {
+		if schema, ok := param.Get("schema"); ok {
+			schemas = append(schemas, schemaAt{schema, pointer + "/schema"})
+		}
+		for _, contentType := range param.Keys("content") {
+			if schema, ok := param.Get("content", contentType, "schema"); ok {
+				schemas = append(schemas, schemaAt{schema, pointer + pointerTo("content", contentType, "schema")})
+			}
+		}
+	}
+	addResponse := func(resp Any, pointer string) {
+		addParam(resp, pointer)
+		for _, name := range resp.Keys("headers") {
+			header, _ := resp.Get("headers", name)
+			addParam(header, pointer+pointerTo("headers", name))
+		}
 	}
 
-	for _, propName := range s.Keys("properties") {
-		prop, _ := s.Get("properties", propName)
+	doc := Any{p.doc}
+	for _, name := range doc.Keys("components", "schemas") {
+		schema, _ := doc.Get("components", "schemas", name)
+		schemas = append(schemas, schemaAt{schema, pointerTo("components", "schemas", name)})
+	}
+	for _, kind := range []string{"parameters", "headers", "requestBodies"} {
+		for _, name := range doc.Keys("components", kind) {
+			param, _ := doc.Get("components", kind, name)
+			addParam(param, pointerTo("components", kind, name))
+		}
+	}
+	for _, name := range doc.Keys("components", "responses") {
+		resp, _ := doc.Get("components", "responses", name)
+		addResponse(resp, pointerTo("components", "responses", name))
+	}
 
-		p.parseConst(propName, prop)
+	for _, section := range []string{"paths", "webhooks"} {
+		for _, path := range doc.Keys(section) {
+			item, _ := doc.Get(section, path)
+			itemPointer := pointerTo(section, path)
+			for i, param := range item.Slice("parameters") {
+				addParam(param, fmt.Sprintf("%s/parameters/%d", itemPointer, i))
+			}
 
-		schema.Params = append(schema.Params, parseProp(
-			propName,
-			prop,
-			required[propName],
-		))
+			for _, method := range []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"} {
+				op, ok := item.Get(method)
+				if !ok {
+					continue
+				}
+				opPointer := itemPointer + "/" + method
+				for i, param := range op.Slice("parameters") {
+					addParam(param, fmt.Sprintf("%s/parameters/%d", opPointer, i))
+				}
+				if body, ok := op.Get("requestBody"); ok {
+					addParam(body, opPointer+"/requestBody")
+				}
+				for _, status := range op.Keys("responses") {
+					resp, _ := op.Get("responses", status)
+					addResponse(resp, opPointer+pointerTo("responses", status))
+				}
+			}
+		}
 	}
 
-	for _, ref := range []struct {
-		name   string
-		target *[]string
-	}{
-		{"anyOf", &schema.AnyOf},
-		{"allOf", &schema.AllOf},
-		{"oneOf", &schema.OneOf},
-	} {
-		for _, refSchem := range s.Slice(ref.name) {
-			*ref.target = append(
-				*ref.target,
-				strings.TrimPrefix(refSchem.Str("$ref"), "#/components/schemas/"),
-			)
+	for _, s := range schemas {
+		if err := p.checkPattern(s.schema, s.pointer); err != nil {
+			return err
 		}
 	}
 
-	if disc, ok := s.Get("discriminator"); ok {
-		mapping := make(map[string]string)
+	return nil
+}
 
-		if _, ok := disc.Get("mapping"); ok {
-			for _, val := range disc.Keys("mapping") {
-				mapping[val] = disc.Str("mapping", val)
+// checkPattern checks the pattern of a schema and of the schemas it contains
+func (p *parser) checkPattern(schema Any, pointer string) error {
+	if pattern, ok := schema.Get("pattern"); ok {
+		if str, ok := pattern.data.(string); ok {
+			if _, err := regexp.Compile(str); err != nil {
+				return p.errorAt(pointer+"/pattern", fmt.Errorf("unsupported pattern %q: %w", str, err))
 			}
 		}
+	}
 
-		apiName := disc.Str("propertyName")
+	type subSchema struct {
+		schema Any
+		suffix string
+	}
+	var subSchemas []subSchema
+	for _, key := range []string{"items", "additionalProperties", "not"} {
+		if sub, ok := schema.Get(key); ok && isSchema(sub) {
+			subSchemas = append(subSchemas, subSchema{sub, pointerTo(key)})
+		}
+	}
+	for _, key := range []string{"properties", "patternProperties", "$defs"} {
+		for _, name := range schema.Keys(key) {
+			sub, _ := schema.Get(key, name)
+			subSchemas = append(subSchemas, subSchema{sub, pointerTo(key, name)})
+		}
+	}
+	for _, key := range []string{"allOf", "oneOf", "anyOf", "prefixItems"} {
+		for i, sub := range schema.Slice(key) {
+			subSchemas = append(subSchemas, subSchema{sub, fmt.Sprintf("/%s/%d", key, i)})
+		}
+	}
 
-		schema.Discriminator = Discriminator{
-			APIName: apiName,
-			GoName:  goName(apiName),
-			JsName:  apiName,
-			Mapping: mapping,
+	for _, sub := range subSchemas {
+		if err := p.checkPattern(sub.schema, pointer+sub.suffix); err != nil {
+			return err
 		}
 	}
 
-	p.api.Schemas = append(p.api.Schemas, schema)
+	return nil
 }
 
-func (p *parser) parsePaths() error {
-	for _, path := range p.doc.Keys("paths") {
-		subDoc, _ := p.doc.Get("paths", path)
-		err := p.parsePath(path, subDoc)
-		if err != nil {
-			return fmt.Errorf("failed parsing path %s: %w", path, err)
-		}
+// errorAt records that an error occurred at the value a JSON pointer of the
+// document refers to
+func (p *parser) errorAt(pointer string, err error) error {
+	pos, ok := p.positions.lookup(pointer)
+	if !ok {
+		return err
 	}
 
-	return nil
+	return &located{pos: pos, err: err}
 }
 
-var (
-	externalRefRegex = regexp.MustCompile(`^([^#]+)?(#.+)$`)
-	pathParamsRegex  = regexp.MustCompile(`{([^}]+)}`)
-)
+// warn records a warning about the value a JSON pointer of the document
+// refers to
+func (p *parser) warn(pointer, format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if pos, ok := p.positions.lookup(pointer); ok {
+		msg = fmt.Sprintf("%s: %s", pos, msg)
+	}
 
-func (p *parser) parsePath(path string, subDoc Any) (err error) {
-	if ref := subDoc.Str("$ref"); ref != "" {
-		// we need to parse the referenced document
-		matches := externalRefRegex.FindStringSubmatch(ref)
-		if len(matches) != 3 {
-			return fmt.Errorf("invalid reference format %q for path %s", ref, path)
+	p.api.Warnings = append(p.api.Warnings, msg)
+}
+
+func hasString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
 		}
+	}
 
-		if _, ok := p.api.RefDocs[matches[1]]; !ok {
-			var refDoc Map
-			switch {
-			case strings.HasPrefix(matches[1], ".json"):
-				refDoc, err = LoadJSON(matches[1])
-			default:
-				refDoc, err = LoadYAML(matches[1])
-			}
-			if err != nil {
-				return fmt.Errorf("failed loading referenced file %s: %w", matches[1], err)
-			}
+	return false
+}
 
-			refAPI, err := ParseDoc(refDoc)
-			if err != nil {
-				return fmt.Errorf("failed parsing referenced file %s: %w", matches[1], err)
+// parseExternalRefs loads the documents referenced by the schemas, parameters
+// and responses of the parsed document. Schemas of documents in the same
+// directory are merged into the API, while those of documents in other
+// directories are left to the packages generated for them, which the API
+// imports. Documents referenced by path items are handled by parsePath.
+func (p *parser) parseExternalRefs() error {
+	refs := make(map[string]string)
+	for key, val := range p.doc {
+		if key != "paths" {
+			collectRefs(val, pointerTo(fmt.Sprint(key)), refs)
+			continue
+		}
+		for _, path := range p.doc.Keys("paths") {
+			item, _ := p.doc.Get("paths", path)
+			if fields, ok := item.data.(Map); ok {
+				for field, val := range fields {
+					if field != "$ref" {
+						collectRefs(val, pointerTo("paths", path, fmt.Sprint(field)), refs)
+					}
+				}
 			}
-
-			p.api.RefDocs[matches[1]] = refAPI
-			p.api.Schemas = append(p.api.Schemas, refAPI.Schemas...)
-			p.api.Methods = append(p.api.Methods, refAPI.Methods...)
-			p.api.Consts = append(p.api.Consts, refAPI.Consts...)
 		}
-
-		return nil
 	}
 
-	commonParams := subDoc.Slice("parameters")
-	for _, method := range subDoc.Keys() {
-		m, _ := subDoc.Get(method)
-		if m.Str("operationId") == "" {
+	// failures are reported at the first reference to a document
+	var paths []string
+	pointers := make(map[string]string)
+	for pointer, ref := range refs {
+		path, _, err := ResolveRef(p.path, ref)
+		if err != nil {
+			return p.errorAt(pointer, err)
+		}
+		if path == "" || path == p.path {
 			continue
 		}
+		if first, ok := pointers[path]; !ok {
+			paths = append(paths, path)
+			pointers[path] = pointer
+		} else if pointer < first {
+			pointers[path] = pointer
+		}
+	}
+	sort.Strings(paths)
 
-		var apiMethod Method
-		apiMethod.APIName = m.Str("operationId")
-		apiMethod.GoName = goName(apiMethod.APIName)
-		apiMethod.JsName = jsMethod(apiMethod.APIName)
-		apiMethod.Path = path
-		apiMethod.FrmwkPath = pathParamsRegex.ReplaceAllString(path, ":$1")
-		apiMethod.HTTPMethod = strings.ToUpper(method)
-		apiMethod.Summary = m.Str("summary")
-		apiMethod.Description = m.Str("description")
-		apiMethod.InputType = inputType(m)
-		apiMethod.OutputType = outputType(m)
-		_, apiMethod.InputInBody = m.Get("requestBody")
+	for _, path := range paths {
+		dir := filepath.Dir(path)
+		if dir != p.dir() {
+			p.addImport(Import{Name: filepath.Base(dir), Dir: dir})
+		}
 
-		// what is the successful status code for this method?
-		for _, status := range m.Keys("responses") {
-			if strings.HasPrefix(status, "2") {
-				apiMethod.SuccessfulStatus, _ = strconv.Atoi(status)
-			}
+		// documents referencing each other are parsed once, the schemas
+		// of the one being parsed being part of its own API
+		if _, ok := p.api.RefDocs[path]; ok || p.resolver.parsing[path] {
+			continue
 		}
 
-		p.api.Methods = append(p.api.Methods, apiMethod)
+		refAPI, err := parseFile(path, p.resolver, p.root)
+		if err != nil {
+			return p.errorAt(pointers[path], fmt.Errorf("failed parsing referenced file %s: %w", path, err))
+		}
 
-		if apiMethod.InputInBody {
-			// if there are common params, we need to find this schema and
-			// create an extension of it that includes these params
-			if len(commonParams) > 0 {
-				for i, schema := range p.api.Schemas {
-					if schema.GoName == apiMethod.InputType {
-						for _, param := range commonParams {
-							schema.Params = append(schema.Params, parseParam(param, true))
-						}
-					}
+		p.api.RefDocs[path] = refAPI
+		for key, val := range refAPI.RefDocs {
+			p.api.RefDocs[key] = val
+		}
 
-					p.api.Schemas[i] = schema
-				}
-			}
+		if dir != p.dir() {
 			continue
 		}
 
-		schema := Schema{
-			APIName: apiMethod.APIName,
-			GoName:  apiMethod.InputType,
-			JsName:  apiMethod.InputType,
+		for _, schema := range refAPI.Schemas {
+			if !p.api.IsSchema(schema.GoName) {
+				p.api.Schemas = append(p.api.Schemas, schema)
+			}
 		}
-
-		params := append(commonParams, m.Slice("parameters")...)
-		for _, p := range params {
-			schema.Params = append(schema.Params, parseParam(p, false))
+		for _, c := range refAPI.Consts {
+			if !p.api.IsConst(c.Name) {
+				p.api.Consts = append(p.api.Consts, c)
+			}
+		}
+		for _, imp := range refAPI.Imports {
+			p.addImport(imp)
 		}
-
-		p.api.Schemas = append(p.api.Schemas, schema)
 	}
 
 	return nil
 }
 
-func (p *parser) parseEnums() error {
-	constNames := make([]string, len(p.consts))
-	var i int
-	for name := range p.consts {
-		constNames[i] = name
-		i++
+func (p *parser) addImport(imp Import) {
+	for _, existing := range p.api.Imports {
+		if existing.Dir == imp.Dir {
+			return
+		}
 	}
 
-	sort.Strings(constNames)
+	p.api.Imports = append(p.api.Imports, imp)
+	sort.Slice(p.api.Imports, func(i, j int) bool {
+		return p.api.Imports[i].Name < p.api.Imports[j].Name
+	})
+}
 
-	for _, name := range constNames {
-		c := p.consts[name]
+// collectRefs records the references found in a value by the pointer to
+// their $ref field
+func collectRefs(val interface{}, pointer string, refs map[string]string) {
+	switch v := val.(type) {
+	case Map:
+		for key, item := range v {
+			itemPointer := pointer + "/" + escapePointer(fmt.Sprint(key))
+			if ref, ok := item.(string); ok && key == "$ref" {
+				refs[itemPointer] = ref
+				continue
+			}
+			collectRefs(item, itemPointer, refs)
+		}
+	case []interface{}:
+		for i, item := range v {
+			collectRefs(item, fmt.Sprintf("%s/%d", pointer, i), refs)
+		}
+	}
+}
 
-		for i, val := range c.Values {
-			if val.GoName == "" {
-				val.GoName = fmt.Sprintf("%s%s", name, goName(val.APIName))
+func (p *parser) parseSecuritySchemes() error {
+	for _, name := range p.doc.Keys("components", "securitySchemes") {
+		s, _ := p.doc.Get("components", "securitySchemes", name)
+		s, err := p.resolve(s)
+		if err != nil {
+			return p.errorAt(
+				pointerTo("components", "securitySchemes", name),
+				fmt.Errorf("failed parsing security scheme %s: %w", name, err),
+			)
+		}
+
+		scheme := SecurityScheme{
+			APIName:          name,
+			GoName:           goName(name),
+			JsName:           name,
+			Type:             s.Str("type"),
+			Description:      s.Str("description"),
+			In:               s.Str("in"),
+			ParamName:        s.Str("name"),
+			Scheme:           s.Str("scheme"),
+			BearerFormat:     s.Str("bearerFormat"),
+			OpenIDConnectURL: s.Str("openIdConnectUrl"),
+		}
+
+		for _, flowType := range s.Keys("flows") {
+			f, _ := s.Get("flows", flowType)
+			flow := OAuthFlow{
+				Type:             flowType,
+				AuthorizationURL: f.Str("authorizationUrl"),
+				TokenURL:         f.Str("tokenUrl"),
+				RefreshURL:       f.Str("refreshUrl"),
 			}
-			if val.JsName == "" {
-				val.JsName = jsConst(val.APIName)
+			for _, scope := range f.Keys("scopes") {
+				flow.Scopes = append(flow.Scopes, Scope{
+					Name:        scope,
+					Description: f.Str("scopes", scope),
+				})
 			}
-
-			c.Values[i] = val
+			scheme.Flows = append(scheme.Flows, flow)
 		}
 
-		p.api.Consts = append(p.api.Consts, c)
+		p.api.SecuritySchemes = append(p.api.SecuritySchemes, scheme)
+	}
+
+	var err error
+	p.api.Security, err = p.parseSecurity(p.doc.Slice("security"))
+	if err != nil {
+		return fmt.Errorf("failed parsing security requirements: %w", err)
 	}
 
 	return nil
 }
 
-func goName(name string) string {
-	return strings.Replace(
-		strings.Replace(
-			strings.Replace(
-				strings.Title(strings.Replace(
-					strings.Replace(name, "_", " ", -1),
-					"-", " ", -1),
-				),
-				" ", "", -1,
-			), "Url", "URL", -1,
-		), "Id", "ID", -1,
-	)
-}
+// parseSecurity parses a list of alternative security requirements, making
+// sure they refer to declared security schemes
+func (p *parser) parseSecurity(requirements []Any) ([]SecurityRequirement, error) {
+	list := make([]SecurityRequirement, 0, len(requirements))
+	for _, req := range requirements {
+		var requirement SecurityRequirement
+		for _, name := range req.Keys() {
+			if !p.hasSecurityScheme(name) {
+				return nil, fmt.Errorf("unknown security scheme %s", name)
+			}
 
-func parseParam(schema Any, noJSON bool) Param {
-	param := Param{
-		APIName:     schema.Str("name"),
-		SpecName:    "{" + schema.Str("name") + "}",
-		Required:    schema.Bool("required"),
-		AllowEmpty:  schema.Bool("allowEmptyValue"),
-		Description: schema.Str("description"),
-		Deprecated:  schema.Bool("deprecated"),
-		In:          schema.Str("in"),
-		IsArray:     schema.Str("schema", "type") == Array,
+			required := RequiredScheme{
+				APIName: name,
+				GoName:  goName(name),
+			}
+			for _, scope := range req.Slice(name) {
+				required.Scopes = append(required.Scopes, scope.Str())
+			}
+			requirement.Schemes = append(requirement.Schemes, required)
+		}
+		list = append(list, requirement)
 	}
 
-	param.GoName = goName(param.APIName)
-	param.JsName = param.APIName
+	return list, nil
+}
 
-	var tags []string
-	if noJSON {
-		tags = append(tags, `json:"-"`)
-	} else if !param.Required {
-		tags = append(tags, fmt.Sprintf(`json:"%s,omitempty"`, param.APIName))
-	} else {
-		tags = append(tags, fmt.Sprintf(`json:"%s"`, param.APIName))
+// hasSecurityScheme returns whether a security scheme is declared by the
+// document or by the root document
+func (p *parser) hasSecurityScheme(name string) bool {
+	if _, ok := p.doc.Get("components", "securitySchemes", name); ok {
+		return true
 	}
 
-	tags = append(tags, fmt.Sprintf(`lambda:"%s.%s"`, param.In, param.APIName))
-
-	param.Tags = fmt.Sprintf("`%s`", strings.Join(tags, " "))
-
-	schema, _ = schema.Get("schema")
-	param.GoType, param.ArrayItemGoType = goType(param.APIName, schema, param.Required)
-	param.JsType, param.ArrayItemJsType = jsType(param.APIName, schema, param.Required)
-	parseParamValidations(&param, schema)
+	for _, scheme := range p.root.SecuritySchemes {
+		if scheme.APIName == name {
+			return true
+		}
+	}
 
-	return param
+	return false
 }
 
-func parseProp(name string, schema Any, req bool) Param {
-	param := Param{
-		APIName:     name,
-		Required:    req,
-		Description: schema.Str("description"),
-		Deprecated:  schema.Bool("deprecated"),
-		IsArray:     schema.Str("type") == Array,
-	}
-
-	var tags []string
-	if !param.Required {
+func (p *parser) parseSchemas() error {
+	// reusable enums keep their names, so they are declared before any inline
+	// enum can take them
+	for _, name := range p.doc.Keys("components", "schemas") {
+		s, _ := p.doc.Get("components", "schemas", name)
+		if len(s.Slice("enum")) > 0 {
+			_, err := p.parseConst("", name, pointerTo("components", "schemas", name), s)
+			if err != nil {
+				return p.errorAt(
+					pointerTo("components", "schemas", name),
+					fmt.Errorf("failed parsing schema %s: %w", name, err),
+				)
+			}
+		}
+	}
+
+	for _, name := range p.doc.Keys("components", "schemas") {
+		s, _ := p.doc.Get("components", "schemas", name)
+		err := p.parseComponent(name, s)
+		if err != nil {
+			return p.errorAt(
+				pointerTo("components", "schemas", name),
+				fmt.Errorf("failed parsing schema %s: %w", name, err),
+			)
+		}
+	}
+
+	return nil
+}
+
+// containment is a schema holding another one by value, through a field of a
+// struct or as the underlying type of a defined type (field is -1)
+type containment struct {
+	schema int
+	field  int
+}
+
+// breakCycles makes the Go types of recursive schemas finite. Schemas holding
+// themselves by value, directly or through other schemas, get a pointer
+// field instead, e.g. "Parent *Node". Cycles going through defined types
+// only, e.g. "type A B" and "type B A", cannot be broken and are an error.
+func (p *parser) breakCycles() error {
+	index := make(map[string]int, len(p.api.Schemas))
+	for i, schema := range p.api.Schemas {
+		index[schema.GoName] = i
+	}
+
+	// holds lists what each schema holds by value
+	holds := func(i int) (held []containment) {
+		schema := p.api.Schemas[i]
+		if schema.Underlying != nil {
+			if j, ok := index[schema.Underlying.GoType]; ok {
+				held = append(held, containment{j, -1})
+			}
+			return held
+		}
+		for f, param := range schema.Params {
+			if j, ok := index[param.GoType]; ok {
+				held = append(held, containment{j, f})
+			}
+		}
+		return held
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(p.api.Schemas))
+	var path []containment
+
+	var visit func(i int) error
+	visit = func(i int) error {
+		state[i] = visiting
+		for _, next := range holds(i) {
+			switch state[next.schema] {
+			case unvisited:
+				path = append(path, containment{i, next.field})
+				if err := visit(next.schema); err != nil {
+					return err
+				}
+				path = path[:len(path)-1]
+			case visiting:
+				cycle := append([]containment(nil), path...)
+				cycle = append(cycle, containment{i, next.field})
+				for len(cycle) > 0 && cycle[0].schema != next.schema {
+					cycle = cycle[1:]
+				}
+				if err := p.breakCycle(cycle); err != nil {
+					return err
+				}
+			}
+		}
+		state[i] = visited
+		return nil
+	}
+
+	for i := range p.api.Schemas {
+		if state[i] == unvisited {
+			if err := visit(i); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// breakCycle turns the last field of a cycle of schemas holding each other
+// into a pointer
+func (p *parser) breakCycle(cycle []containment) error {
+	for i := len(cycle) - 1; i >= 0; i-- {
+		if cycle[i].field < 0 {
+			continue
+		}
+
+		param := &p.api.Schemas[cycle[i].schema].Params[cycle[i].field]
+		if !param.Pointer {
+			param.Pointer = true
+			param.GoType = "*" + param.GoType
+		}
+		return nil
+	}
+
+	names := make([]string, len(cycle)+1)
+	for i, c := range cycle {
+		names[i] = p.api.Schemas[c.schema].GoName
+	}
+	names[len(cycle)] = names[0]
+
+	return p.errorAt(
+		pointerTo("components", "schemas", names[0]),
+		fmt.Errorf("schemas cannot hold themselves: %s", strings.Join(names, " -> ")),
+	)
+}
+
+// parseComponent parses a reusable schema, along with any schemas nested in
+// its "$defs" keyword (JSON Schema 2020-12)
+func (p *parser) parseComponent(name string, s Any) (err error) {
+	if s.Str("$ref") != "" {
+		// an alias of another schema, which must not lead back to itself
+		_, err = p.resolve(s)
+		if err != nil {
+			return err
+		}
+		return p.parseDefinedType(name, s)
+	}
+
+	typ, _ := schemaType(s)
+	switch typ {
+	case Object:
+		if values, ok := s.Get("additionalProperties"); ok && isSchema(values) && len(s.Keys("properties")) == 0 {
+			err = p.parseDefinedType(name, s)
+		} else {
+			err = p.parseSchema(name, s)
+		}
+	case String, Integer, Number:
+		if len(s.Slice("enum")) > 0 {
+			_, err = p.parseConst("", name, enumPointer("", name), s)
+		} else {
+			err = p.parseDefinedType(name, s)
+		}
+	case Boolean, Array:
+		err = p.parseDefinedType(name, s)
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, def := range s.Keys("$defs") {
+		defSchema, _ := s.Get("$defs", def)
+		err = p.parseComponent(def, defSchema)
+		if err != nil {
+			return fmt.Errorf("failed parsing schema %s: %w", def, err)
+		}
+	}
+
+	return nil
+}
+
+// parseDefinedType parses a reusable schema that isn't an object with
+// properties, e.g. "type Tags []string" or "type UserID int64"
+func (p *parser) parseDefinedType(name string, s Any) error {
+	_, _, err := p.parseInlineEnums("", name, enumPointer("", name), s)
+	if err != nil {
+		return err
+	}
+
+	underlying := parseValue(name, name, name, s)
+	err = p.hoistInline("", underlying, s)
+	if err != nil {
+		return err
+	}
+
+	p.api.Schemas = append(p.api.Schemas, Schema{
+		APIName:     name,
+		GoName:      name,
+		JsName:      name,
+		Description: s.Str("description"),
+		Underlying:  underlying,
+	})
+
+	return nil
+}
+
+func (p *parser) parseSchema(name string, s Any) error {
+	required := make(map[string]bool)
+	for _, param := range s.Slice("required") {
+		required[param.Str()] = true
+	}
+
+	schema := Schema{
+		APIName:     name,
+		GoName:      name,
+		JsName:      name,
+		Description: s.Str("description"),
+		ErrorFormat: s.Str("x-go-error"),
+	}
+
+	for _, propName := range s.Keys("properties") {
+		prop, _ := s.Get("properties", propName)
+
+		from, to, err := p.parseInlineEnums(name, propName, enumPointer(name, propName), prop)
+		if err != nil {
+			return fmt.Errorf("failed parsing property %s: %w", propName, err)
+		}
+
+		param := parseProp(propName, prop, required[propName])
+		renameType(&param, from, to)
+		err = p.hoistInline(name, &param, prop)
+		if err != nil {
+			return err
+		}
+		if param.Pointer && p.isNilable(prop) {
+			// defined slice and map types are nil-able on their own
+			param.Pointer = false
+			param.GoType = param.BaseGoType
+			param.JsType = strings.TrimPrefix(param.JsType, "?")
+		}
+
+		schema.Params = append(schema.Params, param)
+	}
+
+	if values, ok := s.Get("additionalProperties"); ok && len(schema.Params) > 0 && values.data != false {
+		// fixed properties are struct fields, the rest are collected into a
+		// map that isn't itself a JSON property
+		extra := Param{
+			APIName: "additional properties",
+			GoName:  "AdditionalProperties",
+			JsName:  "additionalProperties",
+			Tags:    "`json:\"-\"`",
+			GoType:  "map[string]interface{}",
+			JsType:  "Record<string, any>",
+			IsMap:   true,
+		}
+		if isSchema(values) {
+			from, to, err := p.parseInlineEnums(name, name+"Value", enumPointer("", name)+"/additionalProperties", values)
+			if err != nil {
+				return fmt.Errorf("failed parsing additional properties: %w", err)
+			}
+			extra.Values = parseValue(name+"Value", "additional properties", "AdditionalPropertiesValue", values)
+			renameType(extra.Values, from, to)
+			extra.GoType = "map[string]" + extra.Values.GoType
+			extra.JsType = fmt.Sprintf("Record<string, %s>", extra.Values.JsType)
+			err = p.hoistValues(name, &extra, values)
+			if err != nil {
+				return err
+			}
+		}
+		extra.BaseGoType = extra.GoType
+		schema.AdditionalProperties = &extra
+	}
+
+	for _, ref := range []struct {
+		name   string
+		target *[]string
+	}{
+		{"anyOf", &schema.AnyOf},
+		{"allOf", &schema.AllOf},
+		{"oneOf", &schema.OneOf},
+	} {
+		for _, refSchem := range s.Slice(ref.name) {
+			*ref.target = append(*ref.target, refType(refSchem.Str("$ref")))
+		}
+	}
+
+	if disc, ok := s.Get("discriminator"); ok {
+		mapping := make(map[string]string)
+
+		if _, ok := disc.Get("mapping"); ok {
+			for _, val := range disc.Keys("mapping") {
+				mapping[val] = disc.Str("mapping", val)
+			}
+		}
+
+		apiName := disc.Str("propertyName")
+
+		schema.Discriminator = Discriminator{
+			APIName: apiName,
+			GoName:  goName(apiName),
+			JsName:  apiName,
+			Mapping: mapping,
+		}
+	}
+
+	p.api.Schemas = append(p.api.Schemas, schema)
+
+	return nil
+}
+
+func (p *parser) parsePaths() error {
+	for _, path := range p.doc.Keys("paths") {
+		subDoc, _ := p.doc.Get("paths", path)
+		err := p.parsePath(path, subDoc)
+		if err != nil {
+			return p.errorAt(pointerTo("paths", path), fmt.Errorf("failed parsing path %s: %w", path, err))
+		}
+	}
+
+	return nil
+}
+
+var pathParamsRegex = regexp.MustCompile(`{([^}]+)}`)
+
+func (p *parser) parsePath(path string, subDoc Any) (err error) {
+	if ref := subDoc.Str("$ref"); ref != "" {
+		refPath, _, err := ResolveRef(p.path, ref)
+		if err != nil {
+			return err
+		}
+		if refPath == "" {
+			// path items defined elsewhere in the same document
+			subDoc, err = p.resolve(subDoc)
+			if err != nil {
+				return err
+			}
+			return p.parsePath(path, subDoc)
+		}
+
+		// we need to parse the referenced document, unless it's the one
+		// referencing this one
+		if _, ok := p.api.RefDocs[refPath]; !ok && !p.resolver.parsing[refPath] {
+			refAPI, err := parseFile(refPath, p.resolver, p.root)
+			if err != nil {
+				return p.errorAt(
+					pointerTo("paths", path, "$ref"),
+					fmt.Errorf("failed parsing referenced file %s: %w", refPath, err),
+				)
+			}
+
+			p.api.RefDocs[refPath] = refAPI
+			for key, val := range refAPI.RefDocs {
+				p.api.RefDocs[key] = val
+			}
+			p.api.Schemas = append(p.api.Schemas, refAPI.Schemas...)
+			p.api.Methods = append(p.api.Methods, refAPI.Methods...)
+			p.api.Consts = append(p.api.Consts, refAPI.Consts...)
+		}
+
+		return nil
+	}
+
+	methods, err := p.parseOperations(pointerTo("paths", path), path, subDoc)
+	if err != nil {
+		return err
+	}
+
+	p.api.Methods = append(p.api.Methods, methods...)
+
+	return nil
+}
+
+// parseWebhooks parses the operations of an OpenAPI 3.1 document's webhooks.
+// These are requests initiated by the API provider rather than its clients, so
+// they are kept apart from the API's methods.
+func (p *parser) parseWebhooks() error {
+	for _, name := range p.doc.Keys("webhooks") {
+		subDoc, _ := p.doc.Get("webhooks", name)
+		pointer := pointerTo("webhooks", name)
+		subDoc, err := p.resolve(subDoc)
+		if err != nil {
+			return p.errorAt(pointer, fmt.Errorf("failed parsing webhook %s: %w", name, err))
+		}
+
+		methods, err := p.parseOperations(pointer, "", subDoc)
+		if err != nil {
+			return p.errorAt(pointer, fmt.Errorf("failed parsing webhook %s: %w", name, err))
+		}
+
+		for i := range methods {
+			methods[i].Webhook = name
+		}
+
+		p.api.Webhooks = append(p.api.Webhooks, methods...)
+	}
+
+	return nil
+}
+
+// parseOperations parses the operations of a path item, creating input
+// schemas for them as necessary
+func (p *parser) parseOperations(pointer, path string, subDoc Any) (methods []Method, err error) {
+	commonParams, err := p.resolveAll(subDoc.Slice("parameters"))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, method := range subDoc.Keys() {
+		m, _ := subDoc.Get(method)
+		if m.Str("operationId") == "" {
+			continue
+		}
+		opPointer := pointer + "/" + escapePointer(method)
+
+		opParams, err := p.resolveAll(m.Slice("parameters"))
+		if err != nil {
+			return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing parameters of %s: %w", m.Str("operationId"), err))
+		}
+
+		body, hasBody := m.Get("requestBody")
+		if hasBody {
+			body, err = p.resolve(body)
+			if err != nil {
+				return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing request body of %s: %w", m.Str("operationId"), err))
+			}
+		}
+
+		responses := make(map[string]Any)
+		for _, status := range m.Keys("responses") {
+			resp, _ := m.Get("responses", status)
+			responses[status], err = p.resolve(resp)
+			if err != nil {
+				return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing %s response of %s: %w", status, m.Str("operationId"), err))
+			}
+		}
+
+		var apiMethod Method
+		apiMethod.APIName = m.Str("operationId")
+		apiMethod.GoName = goName(apiMethod.APIName)
+		apiMethod.JsName = jsMethod(apiMethod.APIName)
+		apiMethod.Path = path
+		apiMethod.FrmwkPath = pathParamsRegex.ReplaceAllString(path, ":$1")
+		apiMethod.HTTPMethod = strings.ToUpper(method)
+		apiMethod.Summary = m.Str("summary")
+		apiMethod.Description = m.Str("description")
+		apiMethod.InputType = inputType(apiMethod.APIName, body)
+		if hasBody {
+			contentType, _, _ := preferredContent(body)
+			apiMethod.RequestContent = parseContent(body, contentType, apiMethod.InputType)
+		}
+		apiMethod.InputInBody = hasBody
+		for _, tag := range m.Slice("tags") {
+			apiMethod.Tags = append(apiMethod.Tags, tag.Str())
+		}
+
+		// operations may override the API's security requirements, those
+		// of referenced documents being the root document's
+		apiMethod.Security = p.root.Security
+		if _, ok := m.Get("security"); ok {
+			apiMethod.Security, err = p.parseSecurity(m.Slice("security"))
+			if err != nil {
+				return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing security requirements of %s: %w", apiMethod.APIName, err))
+			}
+		}
+
+		var inlineOutput Any
+		apiMethod.OutputType, inlineOutput = outputType(apiMethod.APIName, responses)
+
+		// inline request and response bodies get schemas named after the
+		// operation
+		if _, inlineInput, ok := preferredContent(body); ok && inlineInput.Str("$ref") == "" {
+			err = p.parseBody(apiMethod.InputType, inlineInput)
+			if err != nil {
+				return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing request body of %s: %w", apiMethod.APIName, err))
+			}
+		}
+		if inlineOutput.data != nil {
+			err = p.parseBody(apiMethod.OutputType, inlineOutput)
+			if err != nil {
+				return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing response of %s: %w", apiMethod.APIName, err))
+			}
+		}
+
+		// what is the successful status code for this method?
+		for _, status := range m.Keys("responses") {
+			if strings.HasPrefix(status, "2") {
+				apiMethod.SuccessfulStatus, _ = strconv.Atoi(status)
+				break
+			}
+		}
+
+		apiMethod.Responses, err = p.parseResponses(apiMethod, responses)
+		if err != nil {
+			return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing responses of %s: %w", apiMethod.APIName, err))
+		}
+
+		methods = append(methods, apiMethod)
+
+		if apiMethod.InputInBody {
+			// if there are common params, we need to find this schema and
+			// create an extension of it that includes these params
+			if len(commonParams) > 0 {
+				for i, schema := range p.api.Schemas {
+					if schema.GoName == apiMethod.InputType && schema.Underlying != nil {
+						p.warn(opPointer,
+							"parameters of %s are not part of its input type %s, which isn't an object",
+							apiMethod.APIName, apiMethod.InputType,
+						)
+					} else if schema.GoName == apiMethod.InputType {
+						for j, param := range commonParams {
+							paramPointer := fmt.Sprintf("%s/parameters/%d", pointer, j)
+							parsed, err := p.parseInputParam(schema.GoName, paramPointer, param, true)
+							if err != nil {
+								return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing parameters of %s: %w", apiMethod.APIName, err))
+							}
+							schema.Params = append(schema.Params, parsed)
+						}
+					}
+
+					p.api.Schemas[i] = schema
+				}
+			}
+			continue
+		}
+
+		schema := Schema{
+			APIName: apiMethod.APIName,
+			GoName:  apiMethod.InputType,
+			JsName:  apiMethod.InputType,
+		}
+
+		params := append(commonParams, opParams...)
+		for j, param := range params {
+			paramPointer := fmt.Sprintf("%s/parameters/%d", pointer, j)
+			if j >= len(commonParams) {
+				paramPointer = fmt.Sprintf("%s/parameters/%d", opPointer, j-len(commonParams))
+			}
+			parsed, err := p.parseInputParam(schema.GoName, paramPointer, param, false)
+			if err != nil {
+				return nil, p.errorAt(opPointer, fmt.Errorf("failed parsing parameters of %s: %w", apiMethod.APIName, err))
+			}
+			schema.Params = append(schema.Params, parsed)
+		}
+
+		p.api.Schemas = append(p.api.Schemas, schema)
+	}
+
+	return methods, nil
+}
+
+// parseTags parses the tags declared by the document, in order, followed by
+// those that methods use without declaring them
+func (p *parser) parseTags() error {
+	declared := make(map[string]bool)
+	for _, tag := range p.doc.Slice("tags") {
+		name := tag.Str("name")
+		declared[name] = true
+		p.api.Tags = append(p.api.Tags, Tag{
+			APIName:     name,
+			GoName:      goName(name),
+			JsName:      name,
+			Description: tag.Str("description"),
+		})
+	}
+
+	var undeclared []string
+	for _, method := range p.api.Methods {
+		for _, name := range method.Tags {
+			if !declared[name] {
+				declared[name] = true
+				undeclared = append(undeclared, name)
+			}
+		}
+	}
+
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		p.api.Tags = append(p.api.Tags, Tag{
+			APIName: name,
+			GoName:  goName(name),
+			JsName:  name,
+		})
+	}
+
+	return nil
+}
+
+// parseInputParam parses a parameter of an operation's input schema, found at
+// pointer, declaring its inline enum if it has one
+func (p *parser) parseInputParam(owner, pointer string, param Any, noJSON bool) (Param, error) {
+	schema, _ := param.Get("schema")
+	schemaPointer := pointer + "/schema"
+	if contentType, content, ok := preferredContent(param); ok {
+		schema = content
+		schemaPointer = pointer + pointerTo("content", contentType, "schema")
+	}
+	from, to, err := p.parseInlineEnums(owner, param.Str("name"), schemaPointer, schema)
+	if err != nil {
+		return Param{}, fmt.Errorf("failed parsing parameter %s: %w", param.Str("name"), err)
+	}
+
+	parsed := parseParam(param, noJSON)
+	renameType(&parsed, from, to)
+
+	return parsed, nil
+}
+
+func (p *parser) parseEnums() error {
+	constNames := make([]string, len(p.consts))
+	var i int
+	for name := range p.consts {
+		constNames[i] = name
+		i++
+	}
+
+	sort.Strings(constNames)
+
+	for _, schema := range p.api.Schemas {
+		if _, ok := p.consts[schema.GoName]; ok {
+			return p.errorAt(p.enumPointers[schema.GoName], fmt.Errorf(
+				"enum %s has the same name as a schema, use x-enum-name to rename it", schema.GoName,
+			))
+		}
+	}
+
+	for _, name := range constNames {
+		c := p.consts[name]
+
+		if owners := p.enumOwners[name]; len(owners) > 1 {
+			p.warn(p.enumPointers[name], "enum %s is shared by %s", name, strings.Join(owners, ", "))
+		}
+
+		for i, val := range c.Values {
+			if c.Type != String {
+				// numbers aren't identifiers on their own, e.g. -1.5
+				// becomes PriorityMinus1_5 and PRIORITY_MINUS1_5
+				number := numberNameReplacer.Replace(val.APIName)
+				if val.GoName == "" {
+					val.GoName = name + number
+				}
+				if val.JsName == "" {
+					val.JsName = jsConst(name + "_" + number)
+				}
+			}
+			if val.GoName == "" {
+				val.GoName = fmt.Sprintf("%s%s", name, goName(val.APIName))
+			}
+			if val.JsName == "" {
+				val.JsName = jsConst(val.APIName)
+			}
+
+			c.Values[i] = val
+		}
+
+		p.api.Consts = append(p.api.Consts, c)
+	}
+
+	return nil
+}
+
+var numberNameReplacer = strings.NewReplacer("-", "Minus", "+", "", ".", "_")
+
+func goName(name string) string {
+	return strings.Replace(
+		strings.Replace(
+			strings.Replace(
+				strings.Title(strings.Replace(
+					strings.Replace(name, "_", " ", -1),
+					"-", " ", -1),
+				),
+				" ", "", -1,
+			), "Url", "URL", -1,
+		), "Id", "ID", -1,
+	)
+}
+
+func parseParam(schema Any, noJSON bool) Param {
+	param := Param{
+		APIName:     schema.Str("name"),
+		SpecName:    "{" + schema.Str("name") + "}",
+		Required:    schema.Bool("required"),
+		AllowEmpty:  schema.Bool("allowEmptyValue"),
+		Description: schema.Str("description"),
+		Deprecated:  schema.Bool("deprecated"),
+		In:          schema.Str("in"),
+	}
+
+	param.GoName = goName(param.APIName)
+	param.JsName = param.APIName
+
+	var tags []string
+	if noJSON {
+		tags = append(tags, `json:"-"`)
+	} else if !param.Required {
+		tags = append(tags, fmt.Sprintf(`json:"%s,omitempty"`, param.APIName))
+	} else {
+		tags = append(tags, fmt.Sprintf(`json:"%s"`, param.APIName))
+	}
+
+	tags = append(tags, fmt.Sprintf(`lambda:"%s.%s"`, param.In, param.APIName))
+
+	// parameters are serialized with the default style of their location
+	// unless told otherwise, only non-default styles are tagged
+	param.Style = schema.Str("style")
+	if param.Style == "" {
+		param.Style = defaultStyle(param.In)
+	}
+	param.Explode = param.Style == "form"
+	if _, ok := schema.Get("explode"); ok {
+		param.Explode = schema.Bool("explode")
+	}
+	param.AllowReserved = schema.Bool("allowReserved")
+	if param.Style != defaultStyle(param.In) ||
+		param.Explode != (param.Style == "form") || param.AllowReserved {
+		style := []string{param.Style}
+		if param.Explode {
+			style = append(style, "explode")
+		}
+		if param.AllowReserved {
+			style = append(style, "allowReserved")
+		}
+		tags = append(tags, fmt.Sprintf(`style:"%s"`, strings.Join(style, ",")))
+	}
+
+	// a parameter may be given as serialized content instead of a schema
+	if contentType, content, ok := preferredContent(schema); ok {
+		param.ContentType = contentType
+		tags = append(tags, fmt.Sprintf(`content:"%s"`, contentType))
+		schema = content
+	} else {
+		schema, _ = schema.Get("schema")
+	}
+
+	param.Tags = fmt.Sprintf("`%s`", strings.Join(tags, " "))
+
+	typ, nullable := schemaType(schema)
+	param.IsArray = typ == Array
+	param.Nullable = nullable
+	param.GoType, param.ArrayItemGoType = goType(param.APIName, schema)
+	param.JsType, param.ArrayItemJsType = jsType(param.APIName, schema)
+	param.IsMap = strings.HasPrefix(param.GoType, "map[")
+	parseParamValidations(&param, schema)
+	parseItems(&param, schema)
+	parseValues(&param, schema)
+	applyOptionality(&param)
+
+	return param
+}
+
+// defaultStyle returns the serialization style of parameters in a location
+func defaultStyle(in string) string {
+	switch in {
+	case "query", "cookie":
+		return "form"
+	case "path", "header":
+		return "simple"
+	default:
+		return ""
+	}
+}
+
+func parseProp(name string, schema Any, req bool) Param {
+	param := Param{
+		APIName:     name,
+		Required:    req,
+		Description: schema.Str("description"),
+		Deprecated:  schema.Bool("deprecated"),
+	}
+
+	typ, nullable := schemaType(schema)
+	param.IsArray = typ == Array
+	param.Nullable = nullable
+
+	var tags []string
+	if !param.Required {
 		tags = append(tags, fmt.Sprintf(`json:"%s,omitempty"`, param.APIName))
 	} else {
 		tags = append(tags, fmt.Sprintf(`json:"%s"`, param.APIName))
@@ -356,114 +1346,379 @@ func parseProp(name string, schema Any, req bool) Param {
 	} else if schema.Bool("additionalProperties") {
 		param.GoType = "map[string]interface{}"
 	} else {
-		param.GoType, param.ArrayItemGoType = goType(param.APIName, schema, param.Required)
+		param.GoType, param.ArrayItemGoType = goType(param.APIName, schema)
 	}
-	param.JsType, param.ArrayItemJsType = jsType(param.APIName, schema, param.Required)
+	param.JsType, param.ArrayItemJsType = jsType(param.APIName, schema)
+	param.IsMap = strings.HasPrefix(param.GoType, "map[")
 	parseParamValidations(&param, schema)
+	parseItems(&param, schema)
+	parseValues(&param, schema)
+	applyOptionality(&param)
 
 	return param
 }
 
+// parseItems describes the items of an array parameter, including their own
+// validations. Items of nested arrays are described recursively.
+func parseItems(param *Param, schema Any) {
+	items, _, ok := arrayItems(schema)
+	if !param.IsArray || !ok {
+		return
+	}
+
+	param.Items = parseValue(param.JsName, "items of "+param.APIName, param.GoName+"Item", items)
+}
+
+// parseValues describes the values of a map, i.e. an object whose
+// additionalProperties is a schema
+func parseValues(param *Param, schema Any) {
+	values, ok := schema.Get("additionalProperties")
+	if !ok || !param.IsMap || !isSchema(values) {
+		return
+	}
+
+	param.Values = parseValue(param.JsName, "values of "+param.APIName, param.GoName+"Value", values)
+}
+
+// arrayItems returns the schema of the items of an array, and its pointer
+// relative to the array. Tuples, whose items are described by prefixItems, only
+// have one if all their items, including those following them, share the same
+// schema.
+func arrayItems(schema Any) (items Any, pointer string, ok bool) {
+	items, ok = schema.Get("items")
+	pointer = "/items"
+	if ok && !isSchema(items) {
+		// items: false closes a tuple, items: true lets anything follow it
+		if items.Bool() {
+			return Any{}, "", false
+		}
+		ok = false
+	}
+
+	for i, prefix := range schema.Slice("prefixItems") {
+		if !ok {
+			items, pointer, ok = prefix, fmt.Sprintf("/prefixItems/%d", i), true
+			continue
+		}
+		if !reflect.DeepEqual(prefix.data, items.data) {
+			return Any{}, "", false
+		}
+	}
+
+	return items, pointer, ok
+}
+
+// parseValue describes an unnamed value, such as the items of an array or
+// the values of a map. name is used for naming enums, label to identify the
+// value in validation errors.
+func parseValue(name, label, goName string, schema Any) *Param {
+	value := Param{
+		APIName:     label,
+		GoName:      goName,
+		JsName:      name,
+		Description: schema.Str("description"),
+		Deprecated:  schema.Bool("deprecated"),
+	}
+
+	typ, nullable := schemaType(schema)
+	value.IsArray = typ == Array
+	value.Nullable = nullable
+	value.GoType, value.ArrayItemGoType = goType(name, schema)
+	value.JsType, value.ArrayItemJsType = jsType(name, schema)
+	value.BaseGoType = value.GoType
+	value.IsMap = strings.HasPrefix(value.GoType, "map[")
+	parseParamValidations(&value, schema)
+	parseItems(&value, schema)
+	parseValues(&value, schema)
+
+	return &value
+}
+
+// isSchema returns whether a keyword such as additionalProperties holds a
+// schema rather than a boolean
+func isSchema(val Any) bool {
+	_, ok := val.data.(Map)
+	return ok
+}
+
+// applyOptionality turns the types of a parameter into pointers (or nullable
+// types in JavaScript) when the parameter's absence must be distinguishable
+// from its zero value: when it's nullable or optional. Optional parameters
+// with a default value are pointers too, the default applying to absent
+// values only. Types that are already nil-able are left as they are.
+func applyOptionality(param *Param) {
+	param.Optional = !param.Required
+	param.BaseGoType = param.GoType
+	param.Pointer = false
+
+	if !param.Nullable && param.Required {
+		return
+	}
+
+	if strings.HasPrefix(param.GoType, "*") ||
+		strings.HasPrefix(param.GoType, "[]") ||
+		strings.HasPrefix(param.GoType, "map[") ||
+		param.GoType == "interface{}" ||
+		param.GoType == "io.Reader" ||
+		param.GoType == "json.RawMessage" {
+		return
+	}
+
+	param.Pointer = true
+	param.GoType = "*" + param.GoType
+	param.JsType = "?" + param.JsType
+}
+
 func parseParamValidations(param *Param, schema Any) {
+	_, param.HasDefault = schema.Get("default")
+	param.Format = schema.Str("format")
+
 	switch param.GoType {
 	case String:
 		if def, ok := schema.Get("default"); ok {
 			param.Default = def.Str()
 		}
+		if c, ok := schema.Get("const"); ok {
+			param.Const = c.Str()
+		}
 		if min, ok := schema.Get("minLength"); ok {
 			param.MinLength = min.Int64()
 		}
 		if max, ok := schema.Get("maxLength"); ok {
 			param.MaxLength = max.Int64()
 		}
-	case Int64, Int32, Uint64, Uint32, Uint16, Uint8:
+		param.Pattern = schema.Str("pattern")
+	case "bool":
 		if def, ok := schema.Get("default"); ok {
-			param.Default = def.Int64()
+			param.Default = def.Bool()
+		}
+		if c, ok := schema.Get("const"); ok {
+			param.Const = c.Bool()
+		}
+	case Int64, Int32, Uint64, Uint32, Uint16, Uint8:
+		parseNumericValidations(param, schema, func(val Any) interface{} {
+			return val.Int64()
+		})
+	case Float32, Float64:
+		parseNumericValidations(param, schema, func(val Any) interface{} {
+			return val.Float64()
+		})
+	}
+
+	if param.IsArray {
+		if min, ok := schema.Get("minItems"); ok {
+			param.MinItems = min.Int64()
+		}
+		if max, ok := schema.Get("maxItems"); ok {
+			param.MaxItems = max.Int64()
+		}
+		param.UniqueItems = schema.Bool("uniqueItems")
+	}
+
+	if param.IsMap {
+		if min, ok := schema.Get("minProperties"); ok {
+			param.MinProperties = min.Int64()
+		}
+		if max, ok := schema.Get("maxProperties"); ok {
+			param.MaxProperties = max.Int64()
 		}
-		if min, ok := schema.Get("minimum"); ok {
-			param.Minimum = min.Int64()
+	}
+
+	if reqIf, ok := schema.Get("x-required-if"); ok {
+		toBe, _ := reqIf.Get("to_be")
+		param.RequiredIf = RequiredIf{
+			Needs: reqIf.Str("needs"),
+			ToBe:  toBe.data,
 		}
-		if max, ok := schema.Get("maximum"); ok {
-			param.Maximum = max.Int64()
+	}
+
+	param.ValidURL = schema.Bool("x-valid-url")
+
+	// defaults of enums, defined types and times are kept as they are, see
+	// API.DefaultValue
+	if def, ok := schema.Get("default"); ok && param.Default == nil {
+		param.Default = def.data
+	}
+
+	if examples := schema.Slice("examples"); len(examples) > 0 {
+		for _, example := range examples {
+			param.Examples = append(param.Examples, example.data)
 		}
+	} else if example, ok := schema.Get("example"); ok {
+		param.Examples = []interface{}{example.data}
+	}
+}
+
+// parseNumericValidations records the default value and numeric constraints
+// of a parameter, using conv to convert them to the parameter's type.
+// Exclusive bounds are booleans modifying minimum and maximum in OpenAPI 3.0,
+// but are bounds of their own in OpenAPI 3.1, both forms are supported.
+func parseNumericValidations(param *Param, schema Any, conv func(Any) interface{}) {
+	if def, ok := schema.Get("default"); ok {
+		param.Default = conv(def)
+	}
+	if c, ok := schema.Get("const"); ok {
+		param.Const = conv(c)
+	}
+	if multipleOf, ok := schema.Get("multipleOf"); ok {
+		param.MultipleOf = conv(multipleOf)
+		// integers may be multiples of fractions too, e.g. of 1.5, which
+		// are kept as floats and checked like those of numbers
+		if f := multipleOf.Float64(); f != math.Trunc(f) {
+			param.MultipleOf = f
+		}
+	}
+
+	for _, bound := range []struct {
+		key       string
+		exclusive string
+		value     *interface{}
+		isExcl    *bool
+	}{
+		{"minimum", "exclusiveMinimum", &param.Minimum, &param.ExclusiveMinimum},
+		{"maximum", "exclusiveMaximum", &param.Maximum, &param.ExclusiveMaximum},
+	} {
+		if val, ok := schema.Get(bound.key); ok {
+			*bound.value = conv(val)
+		}
+
+		excl, ok := schema.Get(bound.exclusive)
+		if !ok {
+			continue
+		}
+
+		if isExcl, isBool := excl.data.(bool); isBool {
+			*bound.isExcl = isExcl && *bound.value != nil
+		} else {
+			*bound.value, *bound.isExcl = conv(excl), true
+		}
+	}
+}
+
+// schemaType returns the type of a schema, and whether it is nullable. In
+// OpenAPI 3.0 nullability is declared via the "nullable" keyword, while 3.1
+// (JSON Schema) declares it by including "null" in an array of types, e.g.
+// ["string", "null"]. Schemas allowing several non-null types have no single
+// type, and an empty string is returned for them.
+func schemaType(schema Any) (typ string, nullable bool) {
+	types := schema.Slice("type")
+	if len(types) == 0 {
+		return schema.Str("type"), schema.Bool("nullable")
 	}
 
-	if reqIf, ok := schema.Get("x-required-if"); ok {
-		toBe, _ := reqIf.Get("to_be")
-		param.RequiredIf = RequiredIf{
-			Needs: reqIf.Str("needs"),
-			ToBe:  toBe.data,
+	for _, t := range types {
+		switch {
+		case t.Str() == "null":
+			nullable = true
+		case typ == "":
+			typ = t.Str()
+		default:
+			return "", nullable
 		}
 	}
 
-	param.ValidURL = schema.Bool("x-valid-url")
+	return typ, nullable
 }
 
-func goType(name string, schema Any, req bool) (typeName, arrayTypeName string) {
-	if schema.Str("$ref") != "" {
-		matches := externalRefRegex.FindStringSubmatch(schema.Str("$ref"))
-		if len(matches) != 3 {
-			panic("Invalid $ref format for " + name)
-		}
+// refName returns the name of the schema a reference points to, which may be
+// a component (#/components/schemas/Name) or a definition nested in another
+// schema (#/components/schemas/Other/$defs/Name)
+func refName(ref string) string {
+	if idx := strings.Index(ref, "#"); idx > -1 {
+		ref = ref[idx:]
+	}
 
-		typeName := strings.TrimPrefix(matches[2], "#/components/schemas/")
-		if matches[1] != "" {
-			typeName = fmt.Sprintf(
-				"%s.%s",
-				filepath.Base(filepath.Dir(matches[1])), typeName,
-			)
-		}
+	tokens, err := ParsePointer(ref)
+	if err != nil || len(tokens) == 0 {
+		return ""
+	}
 
-		if req || (schema.Str("type") == String && schema.Str("format") == "") {
-			return typeName, arrayTypeName
-		} else {
-			return fmt.Sprintf("*%s", typeName), arrayTypeName
+	return tokens[len(tokens)-1]
+}
+
+// isNamedRef returns whether the reference tokens of a JSON pointer point to
+// a reusable object, e.g. a component or a path item, rather than somewhere
+// within one
+func isNamedRef(tokens []string) bool {
+	n := len(tokens)
+	switch {
+	case n == 3 && tokens[0] == "components":
+		return true
+	case n == 2 && (tokens[0] == "paths" || tokens[0] == "webhooks"):
+		return true
+	case n >= 2 && tokens[n-2] == "$defs":
+		return true
+	default:
+		return false
+	}
+}
+
+// refType returns the Go type of the schema a reference points to. Schemas of
+// documents in other directories belong to the package generated for that
+// directory, e.g. common.Error for ../common/errors.yaml#/components/schemas/Error
+func refType(ref string) string {
+	typeName := refName(ref)
+	if idx := strings.Index(ref, "#"); idx > 0 {
+		if dir := filepath.Dir(filepath.FromSlash(ref[:idx])); dir != "." {
+			typeName = fmt.Sprintf("%s.%s", filepath.Base(dir), typeName)
 		}
 	}
 
-	switch schema.Str("type") {
+	return typeName
+}
+
+// goType returns the Go type of a schema. Optionality is not reflected in
+// the type, see applyOptionality.
+func goType(name string, schema Any) (typeName, arrayTypeName string) {
+	if schema.Str("$ref") != "" {
+		return refType(schema.Str("$ref")), arrayTypeName
+	}
+
+	typ, _ := schemaType(schema)
+	switch typ {
 	case String:
 		switch schema.Str("format") {
 		case Date, DateTime:
-			if req {
-				return "time.Time", ""
-			} else {
-				return "*time.Time", ""
-			}
+			return "time.Time", ""
 		case Password:
 			return "[]byte", "byte"
+		case Binary:
+			return "io.Reader", ""
 		default:
 			if len(schema.Slice("enum")) > 0 {
-				enumName := schema.Str("x-enum-name")
-				if enumName != "" {
-					return enumName, ""
-				} else {
-					return goName(name), ""
-				}
-			} else {
-				return "string", ""
+				return enumName(name, schema), ""
 			}
+			return "string", ""
 		}
 	case Integer:
-		return schema.Str("format"), ""
+		if len(schema.Slice("enum")) > 0 {
+			return enumName(name, schema), ""
+		}
+		if format := schema.Str("format"); format != "" {
+			return format, ""
+		}
+		return Int64, ""
 	case Number:
+		if len(schema.Slice("enum")) > 0 {
+			return enumName(name, schema), ""
+		}
 		switch schema.Str("format") {
 		case Float:
-			return "float32", ""
+			return Float32, ""
 		default:
-			return "float64", ""
+			return Float64, ""
 		}
 	case Boolean:
-		if req {
-			return "bool", ""
-		} else {
-			return "*bool", ""
-		}
+		return "bool", ""
 	case Object:
+		if values, ok := schema.Get("additionalProperties"); ok && isSchema(values) {
+			valueType, _ := goType(name, values)
+			return "map[string]" + valueType, ""
+		}
 		return "map[string]interface{}", ""
 	case Array:
-		if items, ok := schema.Get("items"); ok {
-			arrayTypeName, _ = goType(name, items, true)
+		if items, _, ok := arrayItems(schema); ok {
+			arrayTypeName, _ = goType(name, items)
 			return fmt.Sprintf("[]%s", arrayTypeName), arrayTypeName
 		}
 		return "[]interface{}", "interface{}"
@@ -472,58 +1727,69 @@ func goType(name string, schema Any, req bool) (typeName, arrayTypeName string)
 	return "interface{}", ""
 }
 
-func jsType(name string, schema Any, req bool) (typeName string, arrayTypeName string) {
+// enumName returns the name of the type generated for an enum: its
+// x-enum-name, its custom-enum name, or the name of the schema or property it
+// is defined in, in that order of precedence
+func enumName(name string, schema Any) string {
+	if enumName := schema.Str("x-enum-name"); enumName != "" {
+		return enumName
+	}
+	if enumName := schema.Str("custom-enum", "name"); enumName != "" {
+		return enumName
+	}
+	return goName(name)
+}
+
+// jsType returns the JavaScript type of a schema. Like goType, it does not
+// reflect optionality.
+func jsType(name string, schema Any) (typeName string, arrayTypeName string) {
 	if schema.Str("$ref") != "" {
-		typeName = strings.TrimPrefix(schema.Str("$ref"), "#/components/schemas/")
-		if req || (schema.Str("type") == String && schema.Str("format") == "") {
-			return typeName, arrayTypeName
-		} else {
-			return fmt.Sprintf("?%s", typeName), arrayTypeName
-		}
+		return refName(schema.Str("$ref")), arrayTypeName
 	}
 
-	switch schema.Str("type") {
+	typ, _ := schemaType(schema)
+	switch typ {
 	case String:
 		switch schema.Str("format") {
 		case Date, DateTime:
-			if req {
-				return "Date", ""
-			} else {
-				return "?Date", ""
-			}
+			return "Date", ""
 		case Password:
 			return "string", ""
+		case Binary:
+			return "Blob", ""
 		default:
 			if len(schema.Slice("enum")) > 0 {
-				enumName := schema.Str("x-enum-name")
-				if enumName != "" {
-					return enumName, ""
-				} else {
-					return goName(name), ""
-				}
-			} else {
-				return "string", ""
+				return enumName(name, schema), ""
 			}
+			return "string", ""
 		}
 	case Integer, Number:
-		return "number", ""
+		if len(schema.Slice("enum")) > 0 {
+			return enumName(name, schema), ""
+		}
 		return "number", ""
 	case Boolean:
 		return "boolean", ""
+	case Object:
+		if values, ok := schema.Get("additionalProperties"); ok && isSchema(values) {
+			valueType, _ := jsType(name, values)
+			return fmt.Sprintf("Record<string, %s>", valueType), ""
+		}
 	case Array:
-		schema := strings.TrimPrefix(schema.Str("items", "$ref"), "#/components/schemas/")
-		return fmt.Sprintf("Array<%s>", schema), schema
+		if items, _, ok := arrayItems(schema); ok {
+			arrayTypeName, _ = jsType(name, items)
+			return fmt.Sprintf("Array<%s>", arrayTypeName), arrayTypeName
+		}
+		return "Array<any>", "any"
 	}
 
 	return "any", ""
 }
 
-func inputType(m Any) string {
-	title := goName(m.Str("operationId"))
-	inputName := strings.TrimPrefix(
-		m.Str("requestBody", "content", "application/json", "schema", "$ref"),
-		"#/components/schemas/",
-	)
+func inputType(operationID string, body Any) string {
+	title := goName(operationID)
+	_, schema, _ := preferredContent(body)
+	inputName := refType(schema.Str("$ref"))
 	if inputName == "" {
 		inputName = title + "Input"
 	}
@@ -531,59 +1797,631 @@ func inputType(m Any) string {
 	return inputName
 }
 
-func outputType(m Any) string {
-	title := goName(m.Str("operationId"))
+// outputType returns the name of the primary successful response's type,
+// i.e. that of the lowest 2xx status. If the response is defined inline, its
+// schema is returned as well so it can be hoisted into a named schema.
+func outputType(operationID string, responses map[string]Any) (string, Any) {
+	title := goName(operationID)
 	outputName := title + "Output"
-	for _, status := range m.Keys("responses") {
+	var inline Any
+
+	statuses := make([]string, 0, len(responses))
+	for status := range responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	for _, status := range statuses {
+		if strings.HasPrefix(status, "2") {
+			_, schema, ok := preferredContent(responses[status])
+			if ok && schema.Str("$ref") == "" {
+				return title + "Output", schema
+			}
+
+			return refType(schema.Str("$ref")), Any{}
+		}
+	}
+
+	return outputName, inline
+}
+
+// parseResponses describes every response of a method. The primary
+// successful response has the method's output type, other responses defined
+// inline get schemas named after the method and their status, e.g.
+// CreateItem404Response.
+func (p *parser) parseResponses(method Method, responses map[string]Any) ([]Response, error) {
+	statuses := make([]string, 0, len(responses))
+	for status := range responses {
+		statuses = append(statuses, status)
+	}
+	sort.Strings(statuses)
+
+	// the primary response is the first successful one, see outputType
+	var primary string
+	for _, status := range statuses {
 		if strings.HasPrefix(status, "2") {
-			outputName = strings.TrimPrefix(
-				m.Str("responses", status, "content", "application/json", "schema", "$ref"),
-				"#/components/schemas/",
+			primary = status
+			break
+		}
+	}
+
+	var list []Response
+	for _, status := range statuses {
+		resp := responses[status]
+
+		response := Response{
+			Status:      status,
+			Description: resp.Str("description"),
+			IsError: strings.HasPrefix(status, "4") ||
+				strings.HasPrefix(status, "5") ||
+				status == "default",
+		}
+		response.StatusCode, _ = strconv.Atoi(status)
+
+		var schema Any
+		var hasSchema, inline bool
+		response.ContentType, schema, hasSchema = preferredContent(resp)
+
+		switch {
+		case !hasSchema:
+		case status == primary && method.OutputType != "":
+			response.GoType, response.JsType = method.OutputType, method.OutputType
+			inline = schema.Str("$ref") == ""
+		case isInlineObject(schema):
+			typeName := method.GoName + goName(status) + "Response"
+			err := p.parseSchema(typeName, schema)
+			if err != nil {
+				return nil, fmt.Errorf("failed parsing %s response: %w", status, err)
+			}
+			response.GoType, response.JsType = typeName, typeName
+			inline = true
+		default:
+			response.GoType, _ = goType(method.GoName+goName(status)+"Response", schema)
+			response.JsType, _ = jsType(method.GoName+goName(status)+"Response", schema)
+		}
+
+		for _, name := range resp.Keys("headers") {
+			header, _ := resp.Get("headers", name)
+			header, err := p.resolve(header)
+			if err != nil {
+				return nil, fmt.Errorf("failed parsing header %s of %s response: %w", name, status, err)
+			}
+			response.Headers = append(response.Headers, parseHeader(name, header))
+		}
+		// schemas shared with other operations, or with request bodies
+		// which headers would then be bound to, are left alone
+		if inline {
+			p.addHeaders(response.GoType, response.Headers)
+		}
+		response.Content = parseContent(resp, response.ContentType, response.GoType)
+
+		list = append(list, response)
+	}
+
+	return list, nil
+}
+
+// addHeaders extends the schema of an inline response with its headers, so
+// that they can be set and read along with its body. Headers are not part of the JSON
+// representation, and don't replace properties of the same name.
+func (p *parser) addHeaders(typeName string, headers []Param) {
+	for i, schema := range p.api.Schemas {
+		if schema.GoName != typeName || schema.Underlying != nil {
+			continue
+		}
+
+		fields := make(map[string]bool)
+		for _, param := range schema.Params {
+			fields[param.GoName] = true
+		}
+
+		for _, header := range headers {
+			if !fields[header.GoName] {
+				schema.Params = append(schema.Params, header)
+			}
+		}
+
+		p.api.Schemas[i] = schema
+	}
+}
+
+// preferredContent returns the media type and schema of a request or response
+// body, preferring JSON if it has several
+func preferredContent(body Any) (contentType string, schema Any, ok bool) {
+	if schema, ok := body.Get("content", "application/json", "schema"); ok {
+		return "application/json", schema, true
+	}
+
+	for _, contentType := range body.Keys("content") {
+		schema, ok := body.Get("content", contentType, "schema")
+		return contentType, schema, ok
+	}
+
+	return "", Any{}, false
+}
+
+// parseContent describes each media type of a request or response body. The
+// preferred one has the provided type, the types of others are derived from
+// their schemas.
+func parseContent(body Any, preferred, typeName string) (content []MediaType) {
+	for _, contentType := range body.Keys("content") {
+		media := MediaType{ContentType: contentType}
+		if contentType == preferred {
+			media.GoType, media.JsType = typeName, typeName
+		} else if schema, ok := body.Get("content", contentType, "schema"); ok {
+			media.GoType, _ = goType(typeName, schema)
+			media.JsType, _ = jsType(typeName, schema)
+		}
+
+		for _, property := range body.Keys("content", contentType, "encoding") {
+			enc, _ := body.Get("content", contentType, "encoding", property)
+			encoding := Encoding{
+				Property:      property,
+				ContentType:   enc.Str("contentType"),
+				Style:         enc.Str("style"),
+				Explode:       enc.Bool("explode"),
+				AllowReserved: enc.Bool("allowReserved"),
+			}
+			if _, ok := enc.Get("explode"); !ok {
+				// form is the default style, which explodes by default
+				encoding.Explode = encoding.Style == "" || encoding.Style == "form"
+			}
+			media.Encoding = append(media.Encoding, encoding)
+		}
+
+		content = append(content, media)
+	}
+
+	return content
+}
+
+// parseBody declares the type of a request or response body defined inline.
+// Objects become structs and other JSON values defined types, while binary
+// and untyped content is held by the Body field of a struct.
+func (p *parser) parseBody(typeName string, schema Any) error {
+	if isInlineObject(schema) {
+		return p.parseSchema(typeName, schema)
+	}
+
+	bodyType, _ := goType(typeName, schema)
+	if bodyType != "io.Reader" && bodyType != "interface{}" {
+		return p.parseDefinedType(typeName, schema)
+	}
+
+	body := Param{
+		APIName:     "body",
+		GoName:      "Body",
+		JsName:      "body",
+		Description: schema.Str("description"),
+		GoType:      bodyType,
+		BaseGoType:  bodyType,
+		Tags:        "`json:\"-\"`",
+	}
+	body.JsType, _ = jsType(typeName, schema)
+
+	p.api.Schemas = append(p.api.Schemas, Schema{
+		APIName: typeName,
+		GoName:  typeName,
+		JsName:  typeName,
+		Params:  []Param{body},
+	})
+
+	return nil
+}
+
+// parseHeader parses a response header, which is described like a parameter
+// except for its name and location
+func parseHeader(name string, header Any) Param {
+	def := Map{"name": name, "in": "header"}
+	if fields, ok := header.data.(Map); ok {
+		for key, val := range fields {
+			if key != "name" && key != "in" {
+				def[key] = val
+			}
+		}
+	}
+
+	return parseParam(Any{def}, true)
+}
+
+// isInlineObject returns whether a schema is an object with properties that
+// is defined in place rather than referenced
+func isInlineObject(schema Any) bool {
+	typ, _ := schemaType(schema)
+	return schema.Str("$ref") == "" &&
+		typ == Object &&
+		len(schema.Keys("properties")) > 0
+}
+
+// hoistInline synthesizes named schemas for a property that is an inline
+// object, or an array of inline objects, and updates the property's types to
+// refer to them. Names are derived from the owning schema and the property,
+// e.g. SignUpInputAddress or SignUpInputAddressesItem.
+func (p *parser) hoistInline(owner string, param *Param, prop Any) error {
+	if isInlineObject(prop) {
+		typeName := owner + param.GoName
+		err := p.parseSchema(typeName, prop)
+		if err != nil {
+			return fmt.Errorf("failed parsing schema %s: %w", typeName, err)
+		}
+
+		param.GoType, param.JsType = typeName, typeName
+		applyOptionality(param)
+		return nil
+	}
+
+	if items, _, ok := arrayItems(prop); ok && param.IsArray && isInlineObject(items) {
+		typeName := owner + param.GoName + "Item"
+		err := p.parseSchema(typeName, items)
+		if err != nil {
+			return fmt.Errorf("failed parsing schema %s: %w", typeName, err)
+		}
+
+		param.GoType, param.ArrayItemGoType = "[]"+typeName, typeName
+		param.JsType, param.ArrayItemJsType = fmt.Sprintf("Array<%s>", typeName), typeName
+		param.Items.GoType, param.Items.BaseGoType, param.Items.JsType = typeName, typeName, typeName
+		applyOptionality(param)
+	}
+
+	if values, ok := prop.Get("additionalProperties"); ok && param.IsMap {
+		err := p.hoistValues(owner, param, values)
+		if err != nil {
+			return err
+		}
+		applyOptionality(param)
+	}
+
+	return nil
+}
+
+// hoistValues synthesizes a named schema for the values of a map that are
+// inline objects, e.g. SignUpInputLabelsValue
+func (p *parser) hoistValues(owner string, param *Param, values Any) error {
+	if !isInlineObject(values) {
+		return nil
+	}
+
+	typeName := owner + param.GoName + "Value"
+	err := p.parseSchema(typeName, values)
+	if err != nil {
+		return fmt.Errorf("failed parsing schema %s: %w", typeName, err)
+	}
+
+	param.GoType = "map[string]" + typeName
+	param.JsType = fmt.Sprintf("Record<string, %s>", typeName)
+	param.Values.GoType, param.Values.BaseGoType, param.Values.JsType = typeName, typeName, typeName
+
+	return nil
+}
+
+// isNilable returns whether a schema refers to a reusable schema whose Go
+// type is a slice or a map
+func (p *parser) isNilable(schema Any) bool {
+	if schema.Str("$ref") == "" {
+		return false
+	}
+
+	target, err := p.resolve(schema)
+	if err != nil {
+		return false
+	}
+
+	typ, _ := schemaType(target)
+	values, _ := target.Get("additionalProperties")
+	return typ == Array ||
+		(typ == Object && isSchema(values) && len(target.Keys("properties")) == 0)
+}
+
+// resolve follows a reference to a reusable component, e.g.
+// "#/components/parameters/PageSize" or "#/components/responses/NotFound",
+// and returns the referenced object. Objects that are not references are
+// returned as-is.
+func (p *parser) resolve(obj Any) (Any, error) {
+	var chain []string
+	for ref := obj.Str("$ref"); ref != ""; ref = obj.Str("$ref") {
+		if hasString(chain, ref) {
+			return obj, fmt.Errorf(
+				"reference cycle %s", strings.Join(append(chain, ref), " -> "),
 			)
 		}
+		chain = append(chain, ref)
+
+		target, err := p.lookup(ref)
+		if err != nil {
+			return obj, err
+		}
+		obj = target
+	}
+
+	return obj, nil
+}
+
+// lookup returns the value a reference points to, without following the
+// target if it is a reference itself. Values of other documents are copied,
+// with their references made relative to the parsed document.
+func (p *parser) lookup(ref string) (Any, error) {
+	path, fragment, err := ResolveRef(p.path, ref)
+	if err != nil {
+		return Any{}, err
+	}
+
+	doc := p.doc
+	if path != "" && path != p.path {
+		doc, _, err = p.resolver.Load(path)
+		if err != nil {
+			return Any{}, fmt.Errorf("failed loading reference %q: %w", ref, err)
+		}
+	}
+
+	target, err := doc.Pointer(fragment)
+	if err != nil {
+		return Any{}, fmt.Errorf("invalid reference %q: %w", ref, err)
+	}
+
+	if path != "" && path != p.path {
+		target = Any{p.rebase(target.data, path)}
+	}
+
+	return target, nil
+}
+
+// rebase copies a value taken from the document at path, making the
+// references it contains relative to the parsed document
+func (p *parser) rebase(val interface{}, path string) interface{} {
+	switch v := val.(type) {
+	case Map:
+		m := make(Map, len(v))
+		for key, item := range v {
+			ref, isRef := item.(string)
+			if key != "$ref" || !isRef {
+				m[key] = p.rebase(item, path)
+				continue
+			}
+
+			refPath, fragment, err := ResolveRef(path, ref)
+			if err != nil {
+				m[key] = ref
+				continue
+			}
+			if refPath == "" {
+				refPath = path
+			}
+			m[key] = p.relativeRef(refPath, fragment)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			s[i] = p.rebase(item, path)
+		}
+		return s
+	default:
+		return val
+	}
+}
+
+// relativeRef returns a reference to a fragment of the document at path, as
+// written in the parsed document
+func (p *parser) relativeRef(path, fragment string) string {
+	if path == p.path {
+		return fragment
+	}
+
+	rel, err := filepath.Rel(p.dir(), path)
+	if err != nil {
+		return path + fragment
+	}
+
+	return filepath.ToSlash(rel) + fragment
+}
+
+// dir returns the directory relative references of the parsed document are
+// resolved against
+func (p *parser) dir() string {
+	if p.path != "" {
+		return filepath.Dir(p.path)
+	}
+
+	dir, _ := filepath.Abs(".")
+	return dir
+}
+
+// resolveAll resolves every item in a list of objects that may be references
+func (p *parser) resolveAll(objs []Any) ([]Any, error) {
+	resolved := make([]Any, len(objs))
+	for i, obj := range objs {
+		target, err := p.resolve(obj)
+		if err != nil {
+			return nil, err
+		}
+
+		resolved[i] = target
+	}
+
+	return resolved, nil
+}
+
+// enumPointer returns a JSON pointer to the schema declaring an enum of a
+// component or one of its properties, as precisely as it is known
+func enumPointer(owner, propName string) string {
+	if owner == "" {
+		return pointerTo("components", "schemas", propName)
 	}
 
-	return outputName
+	return pointerTo("components", "schemas", owner, "properties", propName)
 }
 
-func (p *parser) parseConst(propName string, prop Any) {
+// parseConst declares the enum of a schema and returns its name. Values are
+// named after the enum and the value, unless names are provided by custom-enum
+// options or the x-enum-varnames extension.
+//
+// Identical enums of the same name are declared once. If an enum defined in
+// place in the owner schema conflicts with another one and wasn't explicitly
+// named, it is renamed with the owner's name as prefix, e.g. PetStatus.
+func (p *parser) parseConst(owner, propName, pointer string, prop Any) (string, error) {
 	enum := prop.Slice("enum")
 	if len(enum) == 0 {
-		return
+		return "", nil
 	}
 
-	var constName string
+	constName := enumName(propName, prop)
 	var constVals []ConstValue
 
-	customEnum, ok := prop.Get("custom-enum")
-	if ok {
-		constName = customEnum.Str("name")
+	constType := String
+	switch typ, _ := schemaType(prop); typ {
+	case Integer:
+		constType = Int64
+	case Number:
+		constType = Float64
+	}
 
+	customEnum, custom := prop.Get("custom-enum")
+	if custom {
 		opts := customEnum.Keys("options")
 		constVals = make([]ConstValue, len(opts))
 		for i, opt := range opts {
 			constVals[i] = ConstValue{
 				APIName:     opt,
+				Value:       constValue(constType, Any{opt}),
 				GoName:      customEnum.Str("options", opt, "go_name"),
 				JsName:      customEnum.Str("options", opt, "js_name"),
 				Description: customEnum.Str("options", opt, "description"),
 			}
 		}
 	} else {
+		varNames := prop.Slice("x-enum-varnames")
+		descriptions := prop.Slice("x-enum-descriptions")
+
 		constVals = make([]ConstValue, len(enum))
 		for i, val := range enum {
+			value := constValue(constType, val)
 			constVals[i] = ConstValue{
-				APIName: val.Str(),
+				APIName: fmt.Sprint(value),
+				Value:   value,
+			}
+			if i < len(varNames) {
+				constVals[i].GoName = constName + goName(varNames[i].Str())
+				constVals[i].JsName = jsConst(varNames[i].Str())
+			}
+			if i < len(descriptions) {
+				constVals[i].Description = descriptions[i].Str()
 			}
 		}
 	}
 
-	constName = goName(propName)
-
-	p.consts[constName] = Const{
-		Name:   constName,
+	c := Const{
+		Type:   constType,
 		Values: constVals,
 	}
+
+	explicit := owner == "" ||
+		prop.Str("x-enum-name") != "" ||
+		prop.Str("custom-enum", "name") != ""
+
+	if existing, ok := p.consts[constName]; ok && !sameEnum(existing, c) {
+		renamed := owner + constName
+		// values named after x-enum-varnames are prefixed with the enum name,
+		// the go_name of custom-enum options is used as it is
+		for i, val := range c.Values {
+			if val.GoName != "" && !custom {
+				c.Values[i].GoName = renamed + strings.TrimPrefix(val.GoName, constName)
+			}
+		}
+		if existing, ok := p.consts[renamed]; explicit || (ok && !sameEnum(existing, c)) {
+			return "", fmt.Errorf("enum %s is declared more than once with different values, use x-enum-name to rename it", constName)
+		}
+
+		p.warn(pointer,
+			"enum %s of %s conflicts with another enum of the same name, renamed it to %s",
+			constName, owner, renamed,
+		)
+		constName = renamed
+	}
+
+	c.Name = constName
+	p.consts[constName] = c
+	if _, ok := p.enumPointers[constName]; !ok {
+		p.enumPointers[constName] = pointer
+	}
+	if owner != "" {
+		p.enumOwners[constName] = append(p.enumOwners[constName], owner)
+	}
+
+	return constName, nil
+}
+
+// sameEnum returns whether two enums are structurally identical, regardless
+// of their names
+func sameEnum(a, b Const) bool {
+	return a.Type == b.Type && reflect.DeepEqual(a.Values, b.Values)
+}
+
+// parseInlineEnums declares the enum defined in place in a schema, or in its
+// array items or map values, which are named after it. It returns the name
+// types refer to the enum by and the name it was declared with, which differ
+// if it had to be renamed.
+func (p *parser) parseInlineEnums(owner, name, pointer string, schema Any) (from, to string, err error) {
+	if len(schema.Slice("enum")) > 0 {
+		to, err = p.parseConst(owner, name, pointer, schema)
+		return enumName(name, schema), to, err
+	}
+
+	if items, itemsPointer, ok := arrayItems(schema); ok {
+		return p.parseInlineEnums(owner, name, pointer+itemsPointer, items)
+	}
+
+	if values, ok := schema.Get("additionalProperties"); ok && isSchema(values) {
+		return p.parseInlineEnums(owner, name, pointer+"/additionalProperties", values)
+	}
+
+	return "", "", nil
+}
+
+// renameType makes a parameter, along with its items and values, refer to a
+// type by a new name
+func renameType(param *Param, from, to string) {
+	if param == nil || from == to {
+		return
+	}
+
+	typeName := regexp.MustCompile(`\b` + regexp.QuoteMeta(from) + `\b`)
+	for _, typ := range []*string{
+		&param.GoType, &param.BaseGoType, &param.JsType,
+		&param.ArrayItemGoType, &param.ArrayItemJsType,
+	} {
+		*typ = typeName.ReplaceAllString(*typ, to)
+	}
+
+	renameType(param.Items, from, to)
+	renameType(param.Values, from, to)
+}
+
+// constValue converts an enum value to the enum's underlying type. Values
+// that are keys of custom-enum options are always strings, so numeric values
+// are parsed from them.
+func constValue(constType string, val Any) interface{} {
+	str, isStr := val.data.(string)
+
+	switch constType {
+	case Int64:
+		if isStr {
+			i, _ := strconv.ParseInt(str, 10, 64)
+			return i
+		}
+		return val.Int64()
+	case Float64:
+		if isStr {
+			f, _ := strconv.ParseFloat(str, 64)
+			return f
+		}
+		return val.Float64()
+	default:
+		if isStr {
+			return str
+		}
+		// e.g. YAML 1.1 reads unquoted on/off as booleans
+		return fmt.Sprint(val.data)
+	}
 }
 
 func jsConst(text string) string {
@@ -598,3 +2436,25 @@ func jsConst(text string) string {
 func jsMethod(text string) string {
 	return strings.Replace(text, "-", "_", -1)
 }
+
+// CommentDesc formats a description as a Go comment indented by indent and
+// wrapped so that no line exceeds lineLength characters.
+func CommentDesc(indent, desc string, lineLength int) string {
+	desc = strings.Replace(strings.TrimSuffix(desc, "\n"), "\n", " ", -1)
+	textLength := lineLength - len(indent) - 3
+	var lines []string
+	for len(desc) > textLength {
+		lastRune := []rune(desc[:textLength])[textLength-1]
+		nextRune := []rune(desc[:textLength+1])[textLength]
+		if !unicode.IsSpace(lastRune) && !unicode.IsSpace(nextRune) {
+			// we're gonna cut the text off mid-word
+			desc = fmt.Sprintf("%s-%s", desc[:textLength-1], desc[textLength-1:])
+		}
+		lines = append(lines, fmt.Sprintf("%s// %s", indent, desc[:textLength]))
+		desc = desc[textLength:]
+	}
+	if len(desc) > 0 {
+		lines = append(lines, fmt.Sprintf("%s// %s", indent, desc))
+	}
+	return strings.Join(lines, "\n")
+}
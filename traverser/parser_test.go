@@ -1,9 +1,15 @@
 package traverser
 
 import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/jgroeneveld/trial/assert"
+	"gopkg.in/yaml.v2"
 )
 
 const long = `    // this is a very long line that is 
@@ -20,3 +26,1498 @@ func TestCommentDesc(t *testing.T) {
 	str = CommentDesc("    ", "this is short enough", 40)
 	assert.Equal(t, short, str, "un-shortened comment must be correct")
 }
+
+func parseYAML(t *testing.T, src string) API {
+	t.Helper()
+
+	var doc Map
+	err := yaml.Unmarshal([]byte(src), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+
+	api, err := ParseDoc(doc)
+	assert.MustBeNil(t, err, "document must be parsed successfully")
+
+	return api
+}
+
+const componentRefsDoc = `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: refs }
+paths:
+    /items:
+        parameters:
+            - $ref: "#/components/parameters/Tenant"
+        get:
+            operationId: list-items
+            parameters:
+                - $ref: "#/components/parameters/PageSize"
+            responses:
+                '200':
+                    $ref: "#/components/responses/ItemList"
+        post:
+            operationId: create-item
+            requestBody:
+                $ref: "#/components/requestBodies/NewItem"
+            responses:
+                '201':
+                    description: created
+components:
+    parameters:
+        Tenant:
+            name: tenant
+            in: header
+            required: true
+            schema: { type: string }
+        PageSize:
+            name: page_size
+            in: query
+            schema: { type: integer, format: int64, maximum: 100 }
+    requestBodies:
+        NewItem:
+            content:
+                application/json:
+                    schema:
+                        $ref: "#/components/schemas/Item"
+    responses:
+        ItemList:
+            description: a list of items
+            content:
+                application/json:
+                    schema:
+                        $ref: "#/components/schemas/ItemList"
+    schemas:
+        Item:
+            type: object
+            properties:
+                name: { type: string }
+        ItemList:
+            type: object
+            properties:
+                items:
+                    type: array
+                    items: { $ref: "#/components/schemas/Item" }
+`
+
+func TestComponentRefs(t *testing.T) {
+	api := parseYAML(t, componentRefsDoc)
+
+	list := api.GetSchema("ListItemsInput")
+	assert.Equal(t, 2, len(list.Params), "path and operation parameters must be resolved")
+	assert.Equal(t, "tenant", list.Params[0].APIName)
+	assert.True(t, list.Params[0].Required, "tenant must be required")
+	assert.Equal(t, "page_size", list.Params[1].APIName)
+	assert.Equal(t, "*int64", list.Params[1].GoType)
+	assert.Equal(t, int64(100), list.Params[1].Maximum)
+
+	assert.Equal(t, 2, len(api.Methods))
+	assert.Equal(t, "Item", api.Methods[1].InputType, "request body reference must be resolved")
+	assert.True(t, api.Methods[1].InputInBody, "create-item must have a request body")
+	assert.Equal(t, "ItemList", api.Methods[0].OutputType, "response reference must be resolved")
+
+	item := api.GetSchema("Item")
+	assert.Equal(t, "tenant", item.GetParam("tenant").APIName, "common params must extend the body schema")
+}
+
+const inlineObjectsDoc = `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: inline }
+paths:
+    /signup:
+        post:
+            operationId: sign-up
+            requestBody:
+                content:
+                    application/json:
+                        schema:
+                            type: object
+                            required: [address]
+                            properties:
+                                address:
+                                    type: object
+                                    properties:
+                                        city: { type: string }
+                                phones:
+                                    type: array
+                                    items:
+                                        type: object
+                                        properties:
+                                            number: { type: string }
+            responses:
+                '200':
+                    description: ok
+                    content:
+                        application/json:
+                            schema:
+                                type: object
+                                properties:
+                                    message: { type: string }
+`
+
+func TestInlineObjects(t *testing.T) {
+	api := parseYAML(t, inlineObjectsDoc)
+
+	assert.Equal(t, "SignUpInput", api.Methods[0].InputType)
+	assert.Equal(t, "SignUpOutput", api.Methods[0].OutputType)
+
+	input := api.GetSchema("SignUpInput")
+	assert.Equal(t, "SignUpInputAddress", input.GetParam("address").GoType)
+	assert.Equal(t, "[]SignUpInputPhonesItem", input.GetParam("phones").GoType)
+	assert.Equal(t, "SignUpInputPhonesItem", input.GetParam("phones").ArrayItemGoType)
+
+	assert.Equal(t, "string", api.GetSchema("SignUpInputAddress").GetParam("city").BaseGoType)
+	assert.Equal(t, "string", api.GetSchema("SignUpInputPhonesItem").GetParam("number").BaseGoType)
+	assert.Equal(t, "string", api.GetSchema("SignUpOutput").GetParam("message").BaseGoType)
+}
+
+const openAPI31Doc = `
+openapi: "3.1.0"
+info: { version: 1.0.0, title: modern }
+webhooks:
+    newPet:
+        post:
+            operationId: new-pet
+            requestBody:
+                content:
+                    application/json:
+                        schema:
+                            $ref: "#/components/schemas/Pet/$defs/Tag"
+            responses:
+                '200':
+                    description: ok
+components:
+    schemas:
+        Pet:
+            type: object
+            required: [kind]
+            properties:
+                name:
+                    type: [string, "null"]
+                    examples: [Rex, Fido]
+                kind:
+                    const: dog
+                    type: string
+                vaccinated: { type: boolean, const: false }
+                position:
+                    type: array
+                    prefixItems: [{ type: number }, { type: number }]
+                    items: false
+                sizes:
+                    type: array
+                    prefixItems: [{ type: string, enum: [S, M] }]
+                    items: { type: string, enum: [S, M] }
+                record:
+                    type: array
+                    prefixItems: [{ type: string }, { type: integer }]
+                tag:
+                    $ref: "#/components/schemas/Pet/$defs/Tag"
+            $defs:
+                Tag:
+                    type: object
+                    properties:
+                        label: { type: string }
+`
+
+func TestOpenAPI31(t *testing.T) {
+	api := parseYAML(t, openAPI31Doc)
+
+	assert.Equal(t, "3.1.0", api.OpenAPI)
+
+	pet := api.GetSchema("Pet")
+	name := pet.GetParam("name")
+	assert.Equal(t, "string", name.BaseGoType, "type arrays must be mapped to their non-null type")
+	assert.True(t, name.Nullable, "type arrays including null must be nullable")
+	assert.DeepEqual(t, []interface{}{"Rex", "Fido"}, name.Examples)
+	assert.Equal(t, "dog", pet.GetParam("kind").Const)
+	assert.Equal(t, false, pet.GetParam("vaccinated").Const, "boolean consts must be kept")
+	assert.Equal(t, "[]float64", pet.GetParam("position").GoType, "tuples of one type must be typed")
+	assert.Equal(t, "Array<number>", pet.GetParam("position").JsType)
+	assert.Equal(t, "float64", pet.GetParam("position").Items.GoType)
+	assert.Equal(t, "[]Sizes", pet.GetParam("sizes").GoType)
+	assert.True(t, api.IsConst("Sizes"), "enums of tuple items must be declared")
+	assert.Equal(t, "[]interface{}", pet.GetParam("record").GoType, "tuples of several types must not be typed")
+	assert.True(t, pet.GetParam("record").Items == nil, "tuples of several types must not have items")
+	assert.Equal(t, "*Tag", pet.GetParam("tag").GoType)
+	assert.Equal(t, "label", api.GetSchema("Tag").GetParam("label").APIName, "$defs must be parsed")
+
+	assert.Equal(t, 0, len(api.Methods))
+	assert.Equal(t, 1, len(api.Webhooks))
+	assert.Equal(t, "newPet", api.Webhooks[0].Webhook)
+	assert.Equal(t, "Tag", api.Webhooks[0].InputType)
+}
+
+const optionalityDoc = `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: optionality }
+paths: {}
+components:
+    schemas:
+        Settings:
+            type: object
+            required: [name, nickname, count]
+            properties:
+                name: { type: string }
+                nickname: { type: string, nullable: true }
+                count: { type: integer }
+                limit: { type: integer, default: 10 }
+                enabled: { type: boolean }
+                notify: { type: boolean, default: true }
+                sort: { type: string, enum: [asc, desc], default: asc }
+                since: { type: string, format: date, default: "2020-01-02" }
+                tags: { type: array, items: { type: string } }
+                owner: { $ref: "#/components/schemas/Owner" }
+        Owner:
+            type: object
+            properties:
+                name: { type: string }
+`
+
+func TestOptionality(t *testing.T) {
+	api := parseYAML(t, optionalityDoc)
+	settings := api.GetSchema("Settings")
+
+	for _, tc := range []struct {
+		name     string
+		goType   string
+		jsType   string
+		pointer  bool
+		optional bool
+	}{
+		{"name", "string", "string", false, false},
+		{"nickname", "*string", "?string", true, false},
+		{"count", "int64", "number", false, false},
+		{"limit", "*int64", "?number", true, true},
+		{"enabled", "*bool", "?boolean", true, true},
+		{"notify", "*bool", "?boolean", true, true},
+		{"sort", "*Sort", "?Sort", true, true},
+		{"tags", "[]string", "Array<string>", false, true},
+		{"owner", "*Owner", "?Owner", true, true},
+	} {
+		param := settings.GetParam(tc.name)
+		assert.Equal(t, tc.goType, param.GoType, "Go type of %s", tc.name)
+		assert.Equal(t, tc.jsType, param.JsType, "JS type of %s", tc.name)
+		assert.Equal(t, tc.pointer, param.Pointer, "pointer-ness of %s", tc.name)
+		assert.Equal(t, tc.optional, param.Optional, "optionality of %s", tc.name)
+	}
+
+	assert.True(t, settings.GetParam("limit").HasDefault, "limit must have a default")
+	assert.Equal(t, true, settings.GetParam("notify").Default, "boolean defaults must be parsed")
+	assert.Equal(t, "int64(10)", api.DefaultValue(settings.GetParam("limit")))
+	assert.Equal(t, "SortAsc", api.DefaultValue(settings.GetParam("sort")), "enum defaults must be their constant")
+	assert.Equal(t, "time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)", api.DefaultValue(settings.GetParam("since")))
+	assert.Equal(t, "bool", settings.GetParam("enabled").BaseGoType)
+}
+
+const numericDoc = `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: numeric }
+paths: {}
+components:
+    schemas:
+        Measure:
+            type: object
+            properties:
+                weight: { type: number, format: float, minimum: 0.5, maximum: 100, exclusiveMaximum: true }
+                ratio: { type: number, multipleOf: 0.25, default: 1 }
+                count: { type: integer, format: int32, exclusiveMinimum: 0, maximum: 10, multipleOf: 2 }
+                steps: { type: integer, multipleOf: 1.5 }
+                pairs: { type: integer, multipleOf: 2.0 }
+`
+
+func TestNumericValidations(t *testing.T) {
+	measure := parseYAML(t, numericDoc).GetSchema("Measure")
+
+	weight := measure.GetParam("weight")
+	assert.Equal(t, "float32", weight.BaseGoType)
+	assert.Equal(t, 0.5, weight.Minimum)
+	assert.False(t, weight.ExclusiveMinimum, "weight's minimum must be inclusive")
+	assert.Equal(t, float64(100), weight.Maximum)
+	assert.True(t, weight.ExclusiveMaximum, "weight's maximum must be exclusive")
+
+	ratio := measure.GetParam("ratio")
+	assert.Equal(t, 0.25, ratio.MultipleOf)
+	assert.Equal(t, float64(1), ratio.Default)
+
+	// numeric exclusive bounds are OpenAPI 3.1 style
+	count := measure.GetParam("count")
+	assert.Equal(t, int64(0), count.Minimum)
+	assert.True(t, count.ExclusiveMinimum, "count's minimum must be exclusive")
+	assert.Equal(t, int64(10), count.Maximum)
+	assert.Equal(t, int64(2), count.MultipleOf)
+
+	assert.Equal(t, 1.5, measure.GetParam("steps").MultipleOf, "fractions must not be truncated")
+	assert.Equal(t, int64(2), measure.GetParam("pairs").MultipleOf)
+}
+
+func TestStringValidations(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: strings }
+paths: {}
+components:
+    schemas:
+        Contact:
+            type: object
+            properties:
+                email: { type: string, format: email }
+                code: { type: string, pattern: '^[A-Z]{2}-\d+$' }
+`)
+
+	contact := api.GetSchema("Contact")
+	assert.Equal(t, "email", contact.GetParam("email").Format)
+	assert.Equal(t, `^[A-Z]{2}-\d+$`, contact.GetParam("code").Pattern)
+	assert.True(t, api.UsesFormat("email"), "API must use the email format")
+	assert.False(t, api.UsesFormat("uuid"), "API must not use the uuid format")
+
+	var doc Map
+	err := yaml.Unmarshal([]byte(`
+openapi: "3.0.0"
+info: { version: 1.0.0, title: strings }
+paths: {}
+components:
+    schemas:
+        Password: { type: string, pattern: '^(?=.*\d).{8,}$' }
+`), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+
+	_, err = ParseDoc(doc)
+	assert.NotNil(t, err, "patterns Go doesn't support must be rejected")
+
+	doc = nil
+	err = yaml.Unmarshal([]byte(`
+openapi: "3.0.0"
+info: { version: 1.0.0, title: strings }
+paths:
+    /users:
+        get:
+            operationId: listUsers
+            parameters:
+                - name: name
+                  in: query
+                  schema: { type: string, pattern: '^(?!admin)' }
+            responses:
+                "200": { description: users }
+components:
+    schemas:
+        Rule: { type: object, properties: { pattern: { type: string } } }
+`), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+
+	_, err = ParseDoc(doc)
+	assert.NotNil(t, err, "patterns of parameter schemas must be checked")
+
+	api = parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: strings }
+paths: {}
+x-lint: { pattern: '^(?=.*\d)' }
+components:
+    schemas:
+        Rule:
+            type: object
+            properties:
+                pattern: { type: string }
+            example: { pattern: '(\w)\1' }
+`)
+	assert.Equal(t, "", api.GetSchema("Rule").GetParam("pattern").Pattern)
+}
+
+func TestArrayValidations(t *testing.T) {
+	bag := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: arrays }
+paths: {}
+components:
+    schemas:
+        Bag:
+            type: object
+            properties:
+                tags:
+                    type: array
+                    minItems: 1
+                    maxItems: 5
+                    uniqueItems: true
+                    items: { type: string, minLength: 2 }
+                matrix:
+                    type: array
+                    items: { type: array, maxItems: 3, items: { type: integer, maximum: 9 } }
+`).GetSchema("Bag")
+
+	tags := bag.GetParam("tags")
+	assert.Equal(t, int64(1), tags.MinItems)
+	assert.Equal(t, int64(5), tags.MaxItems)
+	assert.True(t, tags.UniqueItems, "tags must be unique")
+	assert.MustNotBeNil(t, tags.Items, "tags must describe their items")
+	assert.Equal(t, "string", tags.Items.GoType)
+	assert.Equal(t, int64(2), tags.Items.MinLength)
+
+	matrix := bag.GetParam("matrix")
+	assert.Equal(t, "[][]int64", matrix.GoType)
+	assert.Equal(t, "[]int64", matrix.Items.GoType)
+	assert.Equal(t, int64(3), matrix.Items.MaxItems)
+	assert.Equal(t, "int64", matrix.Items.Items.GoType)
+	assert.Equal(t, int64(9), matrix.Items.Items.Maximum)
+}
+
+func TestTypedMaps(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: maps }
+paths: {}
+components:
+    schemas:
+        Label:
+            type: object
+            properties:
+                value: { type: string }
+        Bag:
+            type: object
+            properties:
+                labels:
+                    type: object
+                    minProperties: 1
+                    maxProperties: 10
+                    additionalProperties: { $ref: "#/components/schemas/Label" }
+                counts:
+                    type: object
+                    additionalProperties: { type: integer, minimum: 1 }
+                inline:
+                    type: object
+                    additionalProperties: { type: object, properties: { a: { type: string } } }
+                free:
+                    type: object
+                    additionalProperties: true
+        Mixed:
+            type: object
+            properties:
+                id: { type: string }
+            additionalProperties: { type: string }
+`)
+
+	bag := api.GetSchema("Bag")
+
+	labels := bag.GetParam("labels")
+	assert.True(t, labels.IsMap, "labels must be a map")
+	assert.Equal(t, "map[string]Label", labels.GoType)
+	assert.Equal(t, "Record<string, Label>", labels.JsType)
+	assert.Equal(t, int64(1), labels.MinProperties)
+	assert.Equal(t, int64(10), labels.MaxProperties)
+	assert.MustNotBeNil(t, labels.Values, "labels must describe their values")
+	assert.Equal(t, "Label", labels.Values.GoType)
+
+	counts := bag.GetParam("counts")
+	assert.Equal(t, "map[string]int64", counts.GoType)
+	assert.Equal(t, int64(1), counts.Values.Minimum)
+
+	inline := bag.GetParam("inline")
+	assert.Equal(t, "map[string]BagInlineValue", inline.GoType)
+	assert.Equal(t, "BagInlineValue", inline.Values.BaseGoType)
+	assert.True(t, api.IsSchema("BagInlineValue"), "inline values must be hoisted")
+
+	free := bag.GetParam("free")
+	assert.Equal(t, "map[string]interface{}", free.GoType)
+	assert.True(t, free.Values == nil, "free-form values must not be described")
+
+	mixed := api.GetSchema("Mixed")
+	assert.Equal(t, 1, len(mixed.Params))
+	assert.MustNotBeNil(t, mixed.AdditionalProperties, "mixed must collect additional properties")
+	assert.Equal(t, "map[string]string", mixed.AdditionalProperties.GoType)
+	assert.Equal(t, "Record<string, string>", mixed.AdditionalProperties.JsType)
+}
+
+func TestDefinedTypes(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: defined }
+paths: {}
+components:
+    schemas:
+        Tags:
+            type: array
+            maxItems: 3
+            items: { type: string }
+        UserID:
+            type: integer
+            format: int64
+            minimum: 1
+        Pets:
+            type: array
+            items: { type: object, properties: { name: { type: string } } }
+        User:
+            type: object
+            properties:
+                id: { $ref: "#/components/schemas/UserID" }
+                tags: { $ref: "#/components/schemas/Tags" }
+`)
+
+	tags := api.GetSchema("Tags")
+	assert.MustNotBeNil(t, tags.Underlying, "array schemas must be defined types")
+	assert.Equal(t, "[]string", tags.Underlying.GoType)
+	assert.Equal(t, int64(3), tags.Underlying.MaxItems)
+
+	userID := api.GetSchema("UserID")
+	assert.MustNotBeNil(t, userID.Underlying, "integer schemas must be defined types")
+	assert.Equal(t, "int64", userID.Underlying.GoType)
+	assert.Equal(t, int64(1), userID.Underlying.Minimum)
+
+	assert.Equal(t, "[]PetsItem", api.GetSchema("Pets").Underlying.GoType)
+	assert.True(t, api.IsSchema("PetsItem"), "inline items must be hoisted")
+
+	user := api.GetSchema("User")
+	assert.True(t, user.Underlying == nil, "objects must not be defined types")
+	assert.Equal(t, "*UserID", user.GetParam("id").GoType)
+	assert.Equal(t, "Tags", user.GetParam("tags").GoType, "defined slices must not be pointers")
+}
+
+func TestNumericEnums(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: enums }
+paths: {}
+components:
+    schemas:
+        Priority:
+            type: integer
+            enum: [1, 2, -1]
+        Task:
+            type: object
+            properties:
+                priority: { $ref: "#/components/schemas/Priority" }
+                ratio: { type: number, enum: [0.5, 2] }
+`)
+
+	priority := api.GetConst("Priority")
+	assert.Equal(t, Int64, priority.Type)
+	assert.Equal(t, 3, len(priority.Values))
+	assert.Equal(t, int64(-1), priority.Values[2].Value)
+	assert.Equal(t, "-1", priority.Values[2].APIName)
+	assert.Equal(t, "PriorityMinus1", priority.Values[2].GoName)
+	assert.Equal(t, "PRIORITY_MINUS1", priority.Values[2].JsName)
+	assert.Equal(t, "Priority2", api.FindConst("Priority", 2).GoName)
+
+	ratio := api.GetConst("Ratio")
+	assert.Equal(t, Float64, ratio.Type)
+	assert.Equal(t, 0.5, ratio.Values[0].Value)
+	assert.Equal(t, "Ratio0_5", ratio.Values[0].GoName)
+	assert.Equal(t, "*Ratio", api.GetSchema("Task").GetParam("ratio").GoType)
+	assert.Equal(t, "?Ratio", api.GetSchema("Task").GetParam("ratio").JsType)
+}
+
+func TestEnumNaming(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: enums }
+paths: {}
+components:
+    schemas:
+        Priority:
+            type: integer
+            enum: [1, 2]
+            x-enum-varnames: [low, high]
+            x-enum-descriptions: [not urgent, drop everything]
+        Task:
+            type: object
+            properties:
+                state:
+                    type: string
+                    enum: [open, closed]
+                    x-enum-name: TaskState
+                color:
+                    type: string
+                    enum: [red]
+                    custom-enum:
+                        name: Colour
+                        options:
+                            red: { go_name: ColourRed, js_name: RED }
+                kinds:
+                    type: array
+                    items: { type: string, enum: [a, b] }
+`)
+
+	priority := api.GetConst("Priority")
+	assert.Equal(t, "PriorityLow", priority.Values[0].GoName)
+	assert.Equal(t, "HIGH", priority.Values[1].JsName)
+	assert.Equal(t, "drop everything", priority.Values[1].Description)
+
+	task := api.GetSchema("Task")
+	assert.Equal(t, "*TaskState", task.GetParam("state").GoType)
+	assert.True(t, api.IsConst("TaskState"), "x-enum-name must name the enum")
+	assert.Equal(t, "*Colour", task.GetParam("color").GoType)
+	assert.Equal(t, "ColourRed", api.FindConst("Colour", "red").GoName)
+	assert.Equal(t, "[]Kinds", task.GetParam("kinds").GoType)
+	assert.True(t, api.IsConst("Kinds"), "enums of array items must be declared")
+
+	var doc Map
+	err := yaml.Unmarshal([]byte(`
+openapi: "3.0.0"
+info: { version: 1.0.0, title: enums }
+paths: {}
+components:
+    schemas:
+        Status:
+            type: string
+            enum: [on, off]
+        Pet:
+            type: object
+            properties:
+                status: { type: string, enum: [available, sold], x-enum-name: Status }
+`), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+
+	_, err = ParseDoc(doc)
+	assert.NotNil(t, err, "different enums explicitly given the same name must be rejected")
+}
+
+func TestEnumDeduplication(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: enums }
+paths: {}
+components:
+    schemas:
+        Order:
+            type: object
+            properties:
+                status: { type: string, enum: [placed, shipped] }
+                size: { type: string, enum: [s, m, l] }
+        Pet:
+            type: object
+            properties:
+                status: { type: string, enum: [available, sold] }
+                size: { type: string, enum: [s, m, l] }
+        Status:
+            type: string
+            enum: [active, inactive]
+`)
+
+	assert.Equal(t, 4, len(api.Consts), "identical enums must be declared once")
+	assert.True(t, api.IsConst("Size"), "shared enums must keep their name")
+	assert.True(t, api.IsConst("Status"), "reusable enums must keep their name")
+	assert.Equal(t, "*OrderStatus", api.GetSchema("Order").GetParam("status").GoType)
+	assert.Equal(t, "*PetStatus", api.GetSchema("Pet").GetParam("status").GoType)
+	assert.Equal(t, "*Size", api.GetSchema("Pet").GetParam("size").GoType)
+	assert.DeepEqual(t, []string{
+		"enum Status of Order conflicts with another enum of the same name, renamed it to OrderStatus",
+		"enum Status of Pet conflicts with another enum of the same name, renamed it to PetStatus",
+		"enum Size is shared by Order, Pet",
+	}, api.Warnings)
+
+	api = parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: enums }
+paths: {}
+components:
+    schemas:
+        A:
+            type: object
+            properties:
+                status: { type: integer, enum: [0, 1], x-enum-varnames: [enabled, disabled] }
+        B:
+            type: object
+            properties:
+                status: { type: integer, enum: [1, 2], x-enum-varnames: [enabled, disabled] }
+`)
+
+	assert.Equal(t, "StatusEnabled", api.FindConst("Status", "0").GoName)
+	assert.Equal(t, "BStatusEnabled", api.FindConst("BStatus", "1").GoName,
+		"values of renamed enums must be named after them")
+	assert.Equal(t, "BStatusDisabled", api.FindConst("BStatus", "2").GoName)
+
+	api = parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: enums }
+paths: {}
+components:
+    schemas:
+        A:
+            type: object
+            properties:
+                state: { type: string, enum: [up, down] }
+        B:
+            type: object
+            properties:
+                state:
+                    type: string
+                    enum: [open, closed]
+                    custom-enum:
+                        options:
+                            open: { go_name: DoorOpen }
+                            closed: { go_name: DoorClosed }
+`)
+
+	assert.Equal(t, "DoorOpen", api.FindConst("BState", "open").GoName,
+		"explicit names of renamed enums' values must be kept")
+	assert.Equal(t, "DoorClosed", api.FindConst("BState", "closed").GoName)
+}
+
+func TestResponses(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: responses }
+paths:
+    /items:
+        post:
+            operationId: create-item
+            responses:
+                '202':
+                    description: queued
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/Job" }
+                '201':
+                    description: created
+                    headers:
+                        Location: { schema: { type: string } }
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/Item" }
+                '404':
+                    description: not found
+                    content:
+                        application/json:
+                            schema:
+                                type: object
+                                properties:
+                                    message: { type: string }
+                default:
+                    description: unexpected error
+                    content:
+                        text/plain:
+                            schema: { type: string }
+components:
+    schemas:
+        Item:
+            type: object
+            properties:
+                name: { type: string }
+        Job:
+            type: object
+            properties:
+                id: { type: string }
+`)
+
+	method := api.Methods[0]
+	assert.Equal(t, "Item", method.OutputType, "the lowest 2xx response must be primary")
+	assert.Equal(t, 201, method.SuccessfulStatus)
+	assert.Equal(t, 4, len(method.Responses))
+
+	created := method.Responses[0]
+	assert.Equal(t, 201, created.StatusCode)
+	assert.Equal(t, "created", created.Description)
+	assert.Equal(t, "application/json", created.ContentType)
+	assert.Equal(t, "Item", created.GoType)
+	assert.False(t, created.IsError, "201 must not be an error")
+	assert.Equal(t, 1, len(created.Headers))
+	assert.Equal(t, "Location", created.Headers[0].APIName)
+
+	assert.Equal(t, "Job", method.Responses[1].GoType)
+
+	notFound := method.Responses[2]
+	assert.True(t, notFound.IsError, "404 must be an error")
+	assert.Equal(t, "CreateItem404Response", notFound.GoType)
+	assert.True(t, api.IsSchema("CreateItem404Response"), "inline error responses must be hoisted")
+
+	unexpected := method.Responses[3]
+	assert.Equal(t, "default", unexpected.Status)
+	assert.Equal(t, 0, unexpected.StatusCode)
+	assert.True(t, unexpected.IsError, "the default response must be an error")
+	assert.Equal(t, "text/plain", unexpected.ContentType)
+	assert.Equal(t, "string", unexpected.GoType)
+
+	method = parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: responses }
+paths:
+    /items:
+        get:
+            operationId: list-items
+            responses:
+                2XX:
+                    description: ok
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/Item" }
+                default:
+                    description: unexpected error
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/Error" }
+components:
+    schemas:
+        Item:
+            type: object
+            properties:
+                name: { type: string }
+        Error:
+            type: object
+            properties:
+                message: { type: string }
+`).Methods[0]
+
+	assert.Equal(t, "Item", method.Responses[0].GoType, "status ranges must be primary")
+	assert.Equal(t, "Error", method.Responses[1].GoType, "the default response must keep its type")
+}
+
+func TestResponseHeaders(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: headers }
+paths:
+    /items:
+        get:
+            operationId: list-items
+            responses:
+                '200':
+                    description: ok
+                    headers:
+                        X-Next-Cursor: { $ref: "#/components/headers/Cursor" }
+                        X-Rate-Limit: { required: true, schema: { type: integer, format: int32 } }
+                    content:
+                        application/json:
+                            schema:
+                                type: object
+                                properties:
+                                    items: { type: array, items: { type: string } }
+    /items/{id}:
+        get:
+            operationId: get-item
+            parameters:
+                - { name: id, in: path, required: true, schema: { type: string } }
+            responses:
+                '200':
+                    description: ok
+                    headers:
+                        X-Version: { schema: { type: string } }
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/Item" }
+components:
+    schemas:
+        Item:
+            type: object
+            properties:
+                name: { type: string }
+    headers:
+        Cursor:
+            description: cursor of the next page
+            schema: { type: string }
+`)
+
+	headers := api.Methods[0].Responses[0].Headers
+	assert.Equal(t, 2, len(headers))
+	assert.Equal(t, "X-Next-Cursor", headers[0].APIName)
+	assert.Equal(t, "cursor of the next page", headers[0].Description, "header references must be resolved")
+	assert.Equal(t, "*string", headers[0].GoType)
+	assert.Equal(t, "header", headers[1].In)
+	assert.Equal(t, "int32", headers[1].GoType)
+
+	output := api.GetSchema("ListItemsOutput")
+	assert.Equal(t, 3, len(output.Params), "headers must be added to the output schema")
+	assert.Equal(t, "`json:\"-\" lambda:\"header.X-Rate-Limit\"`", output.GetParam("X-Rate-Limit").Tags)
+	assert.Equal(t, 1, len(api.GetSchema("Item").Params), "headers must not be added to shared schemas")
+}
+
+func TestMediaTypes(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: media }
+paths:
+    /photos:
+        put:
+            operationId: upload-photo
+            requestBody:
+                content:
+                    multipart/form-data:
+                        schema:
+                            type: object
+                            properties:
+                                file: { type: string, format: binary }
+                                thumb: { type: string, format: byte }
+                        encoding:
+                            file: { contentType: image/png }
+            responses:
+                '200':
+                    description: ok
+                    content:
+                        application/octet-stream:
+                            schema: { type: string, format: binary }
+    /tags:
+        post:
+            operationId: set-tags
+            requestBody:
+                content:
+                    application/json:
+                        schema: { type: array, items: { type: string } }
+            responses:
+                '204': { description: done }
+`)
+
+	upload := api.Methods[0]
+	assert.Equal(t, "UploadPhotoInput", upload.InputType)
+	assert.Equal(t, 1, len(upload.RequestContent))
+	assert.Equal(t, "multipart/form-data", upload.RequestContent[0].ContentType)
+	assert.DeepEqual(t, []Encoding{{Property: "file", ContentType: "image/png", Explode: true}}, upload.RequestContent[0].Encoding)
+
+	input := api.GetSchema("UploadPhotoInput")
+	assert.Equal(t, "io.Reader", input.GetParam("file").GoType, "binary strings must be streamed")
+	assert.Equal(t, "Blob", input.GetParam("file").JsType)
+	assert.Equal(t, "*string", input.GetParam("thumb").GoType, "base64 strings must stay strings")
+
+	assert.Equal(t, "UploadPhotoOutput", upload.OutputType)
+	assert.Equal(t, "application/octet-stream", upload.Responses[0].Content[0].ContentType)
+	assert.Equal(t, "io.Reader", api.GetSchema("UploadPhotoOutput").GetParam("body").GoType)
+
+	tags := api.Methods[1]
+	assert.Equal(t, "", tags.OutputType, "responses without content must not have a type")
+	assert.Equal(t, "[]string", api.GetSchema("SetTagsInput").Underlying.GoType)
+}
+
+func TestSecurity(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: secure }
+security:
+    - bearer: []
+paths:
+    /pets:
+        get:
+            operationId: list-pets
+            security:
+                - {}
+                - oauth: ["read:pets"]
+                  key: []
+            responses:
+                '200': { description: ok }
+        post:
+            operationId: create-pet
+            responses:
+                '200': { description: ok }
+    /health:
+        get:
+            operationId: health
+            security: []
+            responses:
+                '200': { description: ok }
+components:
+    securitySchemes:
+        bearer: { type: http, scheme: bearer, bearerFormat: JWT }
+        key: { type: apiKey, in: header, name: X-API-Key }
+        oauth:
+            type: oauth2
+            flows:
+                authorizationCode:
+                    authorizationUrl: https://example.com/auth
+                    tokenUrl: https://example.com/token
+                    scopes: { "read:pets": read pets }
+`)
+
+	assert.Equal(t, 3, len(api.SecuritySchemes))
+	assert.Equal(t, "bearer", api.SecuritySchemes[0].Scheme)
+	assert.Equal(t, "X-API-Key", api.SecuritySchemes[1].ParamName)
+	assert.DeepEqual(t, []OAuthFlow{{
+		Type:             "authorizationCode",
+		AuthorizationURL: "https://example.com/auth",
+		TokenURL:         "https://example.com/token",
+		Scopes:           []Scope{{Name: "read:pets", Description: "read pets"}},
+	}}, api.SecuritySchemes[2].Flows)
+
+	health, listPets, createPet := api.Methods[0], api.Methods[1], api.Methods[2]
+	assert.Equal(t, 0, len(health.Security), "an empty list must make a method public")
+	assert.DeepEqual(t, []SecurityRequirement{
+		{},
+		{Schemes: []RequiredScheme{
+			{APIName: "key", GoName: "Key"},
+			{APIName: "oauth", GoName: "Oauth", Scopes: []string{"read:pets"}},
+		}},
+	}, listPets.Security)
+	assert.DeepEqual(t, api.Security, createPet.Security, "methods must default to the API's requirements")
+}
+
+func TestTags(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: tagged }
+tags:
+    - { name: store }
+    - { name: pets, description: everything about pets }
+paths:
+    /pets:
+        get:
+            operationId: list-pets
+            tags: [pets]
+            responses:
+                '200': { description: ok }
+    /orders:
+        post:
+            operationId: order-pet
+            tags: [pets, store]
+            responses:
+                '200': { description: ok }
+    /health:
+        get:
+            operationId: health
+            tags: [ops-team]
+            responses:
+                '200': { description: ok }
+    /misc:
+        get:
+            operationId: misc
+            responses:
+                '200': { description: ok }
+`)
+
+	assert.DeepEqual(t, []Tag{
+		{APIName: "store", GoName: "Store", JsName: "store"},
+		{APIName: "pets", GoName: "Pets", JsName: "pets", Description: "everything about pets"},
+		{APIName: "ops-team", GoName: "OpsTeam", JsName: "ops-team"},
+	}, api.Tags)
+
+	pets := api.TaggedMethods("pets")
+	assert.Equal(t, 2, len(pets))
+	assert.Equal(t, "OrderPet", pets[0].GoName)
+	assert.Equal(t, "ListPets", pets[1].GoName)
+	assert.DeepEqual(t, []string{"pets", "store"}, pets[0].Tags)
+
+	untagged := api.UntaggedMethods()
+	assert.Equal(t, 1, len(untagged))
+	assert.Equal(t, "Misc", untagged[0].GoName)
+}
+
+func TestParamStyles(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: styles }
+paths:
+    /pets/{id}:
+        get:
+            operationId: getPets
+            parameters:
+                - { name: id, in: path, required: true, schema: { type: string } }
+                - { name: ids, in: query, schema: { type: array, items: { type: integer } } }
+                - { name: tags, in: query, explode: false, schema: { type: array, items: { type: string } } }
+                - name: filter
+                  in: query
+                  style: deepObject
+                  explode: true
+                  schema: { type: object, additionalProperties: { type: string } }
+                - name: where
+                  in: query
+                  content:
+                      application/json:
+                          schema: { type: object, additionalProperties: { type: integer } }
+            responses:
+                '204': { description: ok }
+`)
+
+	input := api.GetSchema("GetPetsInput")
+	id, ids, tags := input.GetParam("id"), input.GetParam("ids"), input.GetParam("tags")
+	filter, where := input.GetParam("filter"), input.GetParam("where")
+
+	assert.Equal(t, "simple", id.Style)
+	assert.True(t, !id.Explode, "path params must not be exploded by default")
+	assert.Equal(t, "form", ids.Style)
+	assert.True(t, ids.Explode, "form params must be exploded by default")
+	assert.Equal(t, "`json:\"ids,omitempty\" lambda:\"query.ids\"`", ids.Tags, "default styles must not be tagged")
+	assert.Equal(t, "`json:\"tags,omitempty\" lambda:\"query.tags\" style:\"form\"`", tags.Tags)
+	assert.Equal(t, "`json:\"filter,omitempty\" lambda:\"query.filter\" style:\"deepObject,explode\"`", filter.Tags)
+	assert.Equal(t, "map[string]string", filter.GoType)
+	assert.Equal(t, "application/json", where.ContentType)
+	assert.Equal(t, "map[string]int64", where.GoType, "content params must take the type of their media type")
+}
+
+// writeFiles writes documents to a temporary directory, which is returned
+// along with a function removing it
+func writeFiles(t *testing.T, files map[string]string) (string, func()) {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "refs")
+	assert.MustBeNil(t, err, "temporary directory must be created")
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		assert.MustBeNil(t, os.MkdirAll(filepath.Dir(path), 0755), "directory must be created")
+		assert.MustBeNil(t, ioutil.WriteFile(path, []byte(content), 0644), "file must be written")
+	}
+
+	return dir, func() { os.RemoveAll(dir) } // nolint: errcheck
+}
+
+func TestExternalPathRefs(t *testing.T) {
+	dir, cleanup := writeFiles(t, map[string]string{
+		"api/openapi.yaml": `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: root }
+security: [bearer: []]
+paths:
+    /users:
+        $ref: ./users/paths.json#/paths/~1users
+    /users/{id}:
+        $ref: users/paths.json#/paths/~1users~1{id}
+components:
+    securitySchemes:
+        bearer: { type: http, scheme: bearer }
+        apiKey: { type: apiKey, in: header, name: X-API-Key }
+`,
+		"api/users/paths.json": `{
+	"openapi": "3.0.0",
+	"info": { "version": "1.0.0", "title": "users" },
+	"paths": {
+		"/users": {
+			"get": {
+				"operationId": "listUsers",
+				"summary": "list users \/ members",
+				"responses": { "204": { "description": "ok" } }
+			}
+		},
+		"/users/{id}": {
+			"get": {
+				"operationId": "getUser",
+				"security": [{ "apiKey": [] }],
+				"responses": { "204": { "description": "ok" } }
+			}
+		}
+	}
+}`,
+	})
+	defer cleanup()
+
+	api, err := ParseFile(filepath.Join(dir, "api", "openapi.yaml"), NewResolver())
+	assert.MustBeNil(t, err, "refs must be resolved relative to the referencing document")
+	assert.Equal(t, 1, len(api.RefDocs), "referenced documents must be loaded once")
+	assert.Equal(t, 2, len(api.Methods))
+	assert.Equal(t, "list users / members", api.Methods[0].Summary, "JSON documents must be decoded")
+	assert.Equal(t, 1, len(api.Methods[0].Security), "referenced operations must inherit the root's security")
+	assert.Equal(t, "bearer", api.Methods[0].Security[0].Schemes[0].APIName)
+	assert.Equal(t, "apiKey", api.Methods[1].Security[0].Schemes[0].APIName,
+		"referenced operations must use the root's security schemes")
+}
+
+func TestExternalSchemaRefs(t *testing.T) {
+	dir, cleanup := writeFiles(t, map[string]string{
+		"api/openapi.yaml": `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: pets }
+paths:
+    /pets:
+        get:
+            operationId: listPets
+            parameters:
+                - $ref: ../common/common.yaml#/components/parameters/Limit
+            responses:
+                '200':
+                    description: ok
+                    content:
+                        application/json:
+                            schema: { $ref: "./types.yaml#/components/schemas/PetList" }
+                default:
+                    $ref: ../common/common.yaml#/components/responses/Error
+components:
+    schemas:
+        Owner:
+            type: object
+            properties:
+                pets: { type: array, items: { $ref: "types.yaml#/components/schemas/Pet" } }
+`,
+		"api/types.yaml": `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: types }
+components:
+    schemas:
+        Pet:
+            type: object
+            properties:
+                name: { type: string }
+        PetList:
+            type: object
+            properties:
+                pets: { type: array, items: { $ref: "#/components/schemas/Pet" } }
+`,
+		"common/common.yaml": `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: common }
+components:
+    parameters:
+        Limit: { name: limit, in: query, schema: { $ref: "#/components/schemas/Limit" } }
+    responses:
+        Error:
+            description: error
+            content:
+                application/json:
+                    schema: { $ref: "#/components/schemas/Error" }
+    schemas:
+        Limit: { type: integer }
+        Error:
+            type: object
+            properties:
+                message: { type: string }
+`,
+	})
+	defer cleanup()
+
+	api, err := ParseFile(filepath.Join(dir, "api", "openapi.yaml"), NewResolver())
+	assert.MustBeNil(t, err, "external refs must be resolved")
+	assert.Equal(t, 2, len(api.RefDocs))
+	assert.True(t, api.IsSchema("Pet"), "schemas of documents in the same directory must be merged")
+	assert.True(t, !api.IsSchema("Error"), "schemas of documents in other directories must not be merged")
+	assert.DeepEqual(t, []Import{{Name: "common", Dir: filepath.Join(dir, "common")}}, api.Imports)
+
+	assert.Equal(t, "[]Pet", api.GetSchema("Owner").GetParam("pets").GoType)
+	assert.Equal(t, "*common.Limit", api.GetSchema("ListPetsInput").GetParam("limit").GoType)
+	method := api.Methods[0]
+	assert.Equal(t, "PetList", method.OutputType)
+	assert.Equal(t, "common.Error", method.Responses[1].GoType)
+}
+
+func TestPointerRefs(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: pointers }
+paths:
+    /users/{id}:
+        get:
+            operationId: getUser
+            parameters:
+                - { name: id, in: path, required: true, schema: { type: string, maxLength: 10 } }
+            responses:
+                '200':
+                    description: ok
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/User" }
+    /users/{id}/friends:
+        get:
+            operationId: getFriends
+            parameters:
+                - $ref: "#/paths/~1users~1%7Bid%7D/get/parameters/0"
+            responses:
+                '204': { description: ok }
+components:
+    schemas:
+        User:
+            type: object
+            properties:
+                address: { type: object, properties: { city: { type: string } } }
+                billing: { $ref: "#/components/schemas/User/properties/address" }
+                id: { $ref: "#/paths/~1users~1{id}/get/parameters/0/schema" }
+`)
+
+	user := api.GetSchema("User")
+	assert.Equal(t, "*string", user.GetParam("id").GoType, "refs within path items must be resolved")
+	assert.Equal(t, int64(10), user.GetParam("id").MaxLength)
+	assert.Equal(t, "*UserBilling", user.GetParam("billing").GoType, "refs to properties must be resolved")
+	assert.Equal(t, "city", api.GetSchema("UserBilling").Params[0].APIName)
+
+	assert.Equal(t, "id", api.GetSchema("GetFriendsInput").GetParam("id").APIName,
+		"escaped refs to parameters must be resolved")
+}
+
+func TestRecursiveSchemas(t *testing.T) {
+	api := parseYAML(t, `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: recursive }
+components:
+    schemas:
+        Node:
+            type: object
+            required: [parent]
+            properties:
+                parent: { $ref: "#/components/schemas/Node" }
+                children: { type: array, items: { $ref: "#/components/schemas/Node" } }
+        Alpha:
+            type: object
+            required: [beta]
+            properties:
+                beta: { $ref: "#/components/schemas/Beta" }
+        Beta:
+            type: object
+            required: [alpha]
+            properties:
+                alpha: { $ref: "#/components/schemas/Alpha" }
+        Tree:
+            type: array
+            items: { $ref: "#/components/schemas/Tree" }
+`)
+
+	node := api.GetSchema("Node")
+	assert.Equal(t, "*Node", node.GetParam("parent").GoType, "self references must be pointers")
+	assert.Equal(t, "[]Node", node.GetParam("children").GoType, "slices must be kept as they are")
+	assert.Equal(t, "Beta", api.GetSchema("Alpha").GetParam("beta").GoType)
+	assert.Equal(t, "*Alpha", api.GetSchema("Beta").GetParam("alpha").GoType,
+		"mutual references must be broken once")
+	assert.Equal(t, "[]Tree", api.GetSchema("Tree").Underlying.GoType)
+
+	var doc Map
+	err := yaml.Unmarshal([]byte(`
+openapi: "3.0.0"
+info: { version: 1.0.0, title: cycle }
+components:
+    schemas:
+        Foo: { $ref: "#/components/schemas/Bar" }
+        Bar: { $ref: "#/components/schemas/Foo" }
+`), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+
+	_, err = ParseDoc(doc)
+	assert.MustNotBeNil(t, err, "reference cycles must fail")
+	assert.Equal(t,
+		"failed parsing schema Bar: reference cycle #/components/schemas/Foo -> #/components/schemas/Bar -> #/components/schemas/Foo",
+		err.Error())
+
+	dir, cleanup := writeFiles(t, map[string]string{
+		"openapi.yaml": `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: root }
+components:
+    schemas:
+        Owner:
+            type: object
+            properties:
+                pets: { type: array, items: { $ref: "pets.yaml#/components/schemas/Pet" } }
+`,
+		"pets.yaml": `
+openapi: "3.0.0"
+info: { version: 1.0.0, title: pets }
+components:
+    schemas:
+        Pet:
+            type: object
+            properties:
+                owner: { $ref: "openapi.yaml#/components/schemas/Owner" }
+`,
+	})
+	defer cleanup()
+
+	api, err = ParseFile(filepath.Join(dir, "openapi.yaml"), NewResolver())
+	assert.MustBeNil(t, err, "documents referencing each other must be parsed")
+	assert.True(t, api.IsSchema("Pet"), "schemas of the referenced document must be merged")
+	assert.Equal(t, "*Owner", api.GetSchema("Pet").GetParam("owner").GoType)
+}
+
+func TestSourcePositions(t *testing.T) {
+	dir, cleanup := writeFiles(t, map[string]string{
+		"openapi.yaml": `openapi: "3.0.0"
+info: { version: 1.0.0, title: positions }
+paths:
+    /pets:
+        get:
+            operationId: listPets
+            parameters:
+                - { name: sort, in: query, schema: { type: string, enum: [asc, desc] } }
+                - { name: kind, in: query, schema: { type: string, enum: [new, old] } }
+            responses:
+                200:
+                    description: ok
+                    content:
+                        application/json:
+                            schema: { $ref: "#/components/schemas/Pet" }
+components:
+    schemas:
+        Pet:
+            type: object
+            properties:
+                kind: { type: string, enum: [cat, dog] }
+        Toy:
+            type: object
+            properties:
+                kind: { type: string, enum: [ball, rope] }
+`,
+		"broken.yaml": `openapi: "3.0.0"
+info: { version: 1.0.0, title: broken }
+components:
+    schemas:
+        Pet:
+            type: object
+            properties:
+                owner: { $ref: "#components/schemas/Owner" }
+`,
+		"broken.json": `{
+	"openapi": "3.0.0",
+	"info": { "version": "1.0.0", "title": "broken" },
+	"components": {
+		"schemas": {
+			"Pet": { "$ref": "other.json" }
+		}
+	}
+}`,
+		"leash.yaml": `openapi: "3.0.0"
+info: { version: 1.0.0, title: leash }
+components:
+    schemas:
+        Leash: { $ref: "broken.yaml#/components/schemas/Pet" }
+`,
+		"collar.yaml": `openapi: "3.0.0"
+info: { version: 1.0.0, title: collar }
+components:
+    schemas:
+        Dog:
+            type: object
+            properties:
+                collar: { $ref: "fragment.yaml#/components/schemas/Collar" }
+`,
+		"fragment.yaml": `components:
+    schemas:
+        Collar:
+            type: object
+            properties:
+                size: { type: integer }
+`,
+		"empty.yaml": ``,
+		"swagger.yaml": `swagger: "2.0"
+info: { version: 1.0.0, title: swagger }
+paths: {}
+definitions:
+    Password:
+        type: string
+        pattern: '^(?=.*\d).{8,}$'
+`,
+		"swagger.json": `{
+	"swagger": "2.0",
+	"info": { "version": "1.0.0", "title": "swagger" },
+	"paths": {
+		"/users": {
+			"get": {
+				"operationId": "listUsers",
+				"parameters": [
+					{ "name": "limit", "in": "query", "type": "integer" },
+					{ "name": "name", "in": "query", "type": "string", "pattern": "^(?!admin)" }
+				],
+				"responses": { "200": { "description": "ok" } }
+			}
+		}
+	}
+}`,
+	})
+	defer cleanup()
+
+	api, err := ParseFile(filepath.Join(dir, "openapi.yaml"), NewResolver())
+	assert.MustBeNil(t, err, "document must be parsed")
+	assert.Equal(t, "Pet", api.Methods[0].OutputType, "unquoted status codes must be parsed")
+	assert.Equal(t, 2, len(api.Warnings))
+	assert.True(t, strings.HasSuffix(api.Warnings[0],
+		"openapi.yaml:25:17: enum Kind of Toy conflicts with another enum of the same name, renamed it to ToyKind",
+	), "warnings must be located, got %s", api.Warnings[0])
+	assert.True(t, strings.HasSuffix(api.Warnings[1],
+		"openapi.yaml:9:44: enum Kind of ListPetsInput conflicts with another enum of the same name, renamed it to ListPetsInputKind",
+	), "warnings of parameters must be located, got %s", api.Warnings[1])
+
+	for name, expected := range map[string]string{
+		"broken.yaml": `broken.yaml:8:26: invalid reference "#components/schemas/Owner"`,
+		"broken.json": `broken.json:6:13: failed loading reference "other.json"`,
+		"leash.yaml":  `broken.yaml:8:26: failed parsing referenced file`,
+		// Swagger 2.0 documents are converted, but located in the original
+		"swagger.yaml": `swagger.yaml:7:9: unsupported pattern`,
+		"swagger.json": `swagger.json:10:57: unsupported pattern`,
+	} {
+		_, err = ParseFile(filepath.Join(dir, name), NewResolver())
+		var srcErr *SourceError
+		assert.MustBeTrue(t, errors.As(err, &srcErr), "%s must fail with a located error", name)
+		assert.True(t, strings.Contains(err.Error(), expected), "unexpected error %s", err)
+	}
+
+	api, err = ParseFile(filepath.Join(dir, "collar.yaml"), NewResolver())
+	assert.MustBeNil(t, err, "referenced documents must not have to be specifications")
+	assert.True(t, api.IsSchema("Collar"), "schemas of fragments must be merged")
+
+	_, err = ParseFile(filepath.Join(dir, "empty.yaml"), NewResolver())
+	assert.MustNotBeNil(t, err, "empty documents must fail")
+	assert.Equal(t, "not an OpenAPI 3 document, the openapi field is missing", err.Error())
+}
@@ -0,0 +1,196 @@
+package traverser
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	yaml3 "gopkg.in/yaml.v3"
+)
+
+// Position is a location in a document
+type Position struct {
+	File   string
+	Line   int
+	Column int
+}
+
+func (pos Position) String() string {
+	return fmt.Sprintf("%s:%d:%d", pos.File, pos.Line, pos.Column)
+}
+
+// SourceError is an error located in a document
+type SourceError struct {
+	Pos Position
+	Err error
+}
+
+func (err *SourceError) Error() string {
+	return fmt.Sprintf("%s: %s", err.Pos, err.Err)
+}
+
+func (err *SourceError) Unwrap() error {
+	return err.Err
+}
+
+// located records where an error occurred while parsing. Errors are wrapped
+// with context on their way up, and eventually reported at the innermost
+// location recorded, see locate.
+type located struct {
+	pos Position
+	err error
+}
+
+func (err *located) Error() string {
+	return err.err.Error()
+}
+
+func (err *located) Unwrap() error {
+	return err.err
+}
+
+// locate turns an error into a SourceError positioned at the innermost
+// location recorded in its chain, if any
+func locate(err error) error {
+	var pos *Position
+	for e := err; e != nil; e = errors.Unwrap(e) {
+		if l, ok := e.(*located); ok {
+			pos = &l.pos
+		}
+	}
+
+	if pos == nil {
+		return err
+	}
+
+	return &SourceError{Pos: *pos, Err: err}
+}
+
+// positions indexes the position of every value of a document by JSON
+// pointer. Values of mappings are positioned at their key.
+type positions map[string]Position
+
+// indexPositions returns the positions of the values of a JSON or YAML
+// document, or nil if the document cannot be decoded
+func indexPositions(file string, data []byte) positions {
+	var root yaml3.Node
+	if err := yaml3.Unmarshal(data, &root); err != nil {
+		return nil
+	}
+
+	index := make(positions)
+	var walk func(node *yaml3.Node, pointer string, at *yaml3.Node)
+	walk = func(node *yaml3.Node, pointer string, at *yaml3.Node) {
+		if at.Line > 0 {
+			index[pointer] = Position{File: file, Line: at.Line, Column: at.Column}
+		}
+
+		switch node.Kind {
+		case yaml3.DocumentNode:
+			for _, child := range node.Content {
+				walk(child, pointer, child)
+			}
+		case yaml3.MappingNode:
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				key := node.Content[i]
+				walk(node.Content[i+1], pointer+"/"+escapePointer(key.Value), key)
+			}
+		case yaml3.SequenceNode:
+			for i, child := range node.Content {
+				walk(child, fmt.Sprintf("%s/%d", pointer, i), child)
+			}
+		}
+	}
+	walk(&root, "", &root)
+
+	return index
+}
+
+// lookup returns the position of the value a JSON pointer refers to, or that
+// of its closest ancestor found in the document
+func (index positions) lookup(pointer string) (Position, bool) {
+	if index == nil {
+		return Position{}, false
+	}
+
+	for {
+		if pos, ok := index[pointer]; ok {
+			return pos, true
+		}
+
+		idx := strings.LastIndex(pointer, "/")
+		if idx < 0 {
+			return Position{}, false
+		}
+		pointer = pointer[:idx]
+	}
+}
+
+// sources maps the pointers of a converted document to those of the values
+// of the original document they were converted from. Values below a pointer
+// come from below its source, unless mapped otherwise.
+type sources map[string]string
+
+// source returns the pointer of the original value a converted one comes from
+func (s sources) source(pointer string) string {
+	prefix := pointer
+	for {
+		if src, ok := s[prefix]; ok {
+			return src + pointer[len(prefix):]
+		}
+
+		idx := strings.LastIndex(prefix, "/")
+		if idx < 0 {
+			return pointer
+		}
+		prefix = prefix[:idx]
+	}
+}
+
+// converted returns the positions of the values of a document converted from
+// the indexed one, such as the OpenAPI 3 conversion of a Swagger 2.0 document
+func (index positions) converted(doc Map, s sources) positions {
+	if index == nil {
+		return nil
+	}
+
+	converted := make(positions)
+	var walk func(val interface{}, pointer string)
+	walk = func(val interface{}, pointer string) {
+		if pos, ok := index[s.source(pointer)]; ok {
+			converted[pointer] = pos
+		}
+
+		switch v := val.(type) {
+		case Map:
+			for key, item := range v {
+				walk(item, pointer+"/"+escapePointer(keyString(key)))
+			}
+		case []interface{}:
+			for i, item := range v {
+				walk(item, fmt.Sprintf("%s/%d", pointer, i))
+			}
+		}
+	}
+	walk(doc, "")
+
+	return converted
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// escapePointer escapes a reference token of a JSON pointer
+func escapePointer(token string) string {
+	return pointerEscaper.Replace(token)
+}
+
+// pointerTo builds a JSON pointer from reference tokens
+func pointerTo(tokens ...string) string {
+	var b strings.Builder
+	for _, token := range tokens {
+		b.WriteString("/")
+		b.WriteString(escapePointer(token))
+	}
+
+	return b.String()
+}
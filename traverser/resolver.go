@@ -0,0 +1,105 @@
+package traverser
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Resolver loads the documents referenced by an API specification. Documents
+// are loaded once, and cached by absolute path.
+type Resolver struct {
+	docs map[string]Map
+	// index holds the positions of the values of each document
+	index map[string]positions
+	// parsing holds the documents being parsed, which documents they
+	// reference, directly or not, must not parse again
+	parsing map[string]bool
+}
+
+func NewResolver() *Resolver {
+	return &Resolver{
+		docs:    make(map[string]Map),
+		index:   make(map[string]positions),
+		parsing: make(map[string]bool),
+	}
+}
+
+// Load loads the document at path, returning it along with its absolute path
+func (r *Resolver) Load(path string) (doc Map, abs string, err error) {
+	abs, err = filepath.Abs(path)
+	if err != nil {
+		return doc, abs, fmt.Errorf("failed resolving %q: %w", path, err)
+	}
+
+	if doc, ok := r.docs[abs]; ok {
+		return doc, abs, nil
+	}
+
+	doc, src, err := loadSpec(abs)
+	if err != nil {
+		return doc, abs, err
+	}
+
+	r.docs[abs] = doc
+
+	// positions are only used for diagnostics, documents that can't be
+	// indexed are parsed all the same
+	if data, err := ioutil.ReadFile(abs); err == nil {
+		index := indexPositions(displayPath(abs), data)
+		if src != nil {
+			index = index.converted(doc, src)
+		}
+		r.index[abs] = index
+	}
+
+	return doc, abs, nil
+}
+
+// displayPath returns the path of a document relative to the working
+// directory if it is within it, for shorter diagnostics
+func displayPath(abs string) string {
+	wd, err := os.Getwd()
+	if err != nil {
+		return abs
+	}
+
+	rel, err := filepath.Rel(wd, abs)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return abs
+	}
+
+	return rel
+}
+
+var externalRefRegex = regexp.MustCompile(`^([^#]*)(#.*)?$`)
+
+// ResolveRef splits a reference into the path of the document it points to
+// and the fragment within it. Relative paths are resolved against the
+// directory of the referencing document at base, or the working directory if
+// base is empty. The path of local references is empty.
+func ResolveRef(base, ref string) (path, fragment string, err error) {
+	matches := externalRefRegex.FindStringSubmatch(ref)
+	if len(matches) != 3 || ref == "" {
+		return "", "", fmt.Errorf("invalid reference format %q", ref)
+	}
+
+	path, fragment = filepath.FromSlash(matches[1]), matches[2]
+	if path == "" {
+		return "", fragment, nil
+	}
+
+	if !filepath.IsAbs(path) && base != "" {
+		path = filepath.Join(filepath.Dir(base), path)
+	}
+
+	path, err = filepath.Abs(path)
+	if err != nil {
+		return "", "", fmt.Errorf("failed resolving %q: %w", ref, err)
+	}
+
+	return path, fragment, nil
+}
@@ -0,0 +1,716 @@
+package traverser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// IsSwagger2 returns whether a document is a Swagger (OpenAPI 2.0) document
+func IsSwagger2(doc Map) bool {
+	version, ok := doc.Get("swagger")
+	// unquoted, the version is decoded as a number, which prints as 2
+	return ok && (fmt.Sprint(version.data) == "2.0" || version.data == 2.0)
+}
+
+// swaggerRefPrefixes maps reference prefixes of Swagger 2.0 documents to their
+// OpenAPI 3 equivalents
+var swaggerRefPrefixes = [][2]string{
+	{"#/definitions/", "#/components/schemas/"},
+	{"#/parameters/", "#/components/parameters/"},
+	{"#/responses/", "#/components/responses/"},
+}
+
+// swaggerParamSchemaKeys are the keys of a Swagger 2.0 non-body parameter that
+// describe its value, and are moved into the parameter's schema in OpenAPI 3
+var swaggerParamSchemaKeys = map[string]bool{
+	"type":             true,
+	"format":           true,
+	"items":            true,
+	"default":          true,
+	"maximum":          true,
+	"exclusiveMaximum": true,
+	"minimum":          true,
+	"exclusiveMinimum": true,
+	"maxLength":        true,
+	"minLength":        true,
+	"pattern":          true,
+	"maxItems":         true,
+	"minItems":         true,
+	"uniqueItems":      true,
+	"enum":             true,
+	"multipleOf":       true,
+}
+
+type swaggerConverter struct {
+	doc      Map
+	consumes []string
+	produces []string
+	sources  sources
+}
+
+// swaggerParam is a parameter of a Swagger 2.0 document, along with its
+// pointer in the document
+type swaggerParam struct {
+	Any
+	pointer string
+}
+
+// ConvertSwagger2 converts a Swagger 2.0 document into an equivalent OpenAPI
+// 3.0 document, so that it can be parsed by ParseDoc.
+func ConvertSwagger2(doc Map) (Map, error) {
+	out, _, err := convertSwagger2(doc)
+	return out, err
+}
+
+// convertSwagger2 converts a Swagger 2.0 document, also returning where the
+// values of the converted document come from in the original one
+func convertSwagger2(doc Map) (Map, sources, error) {
+	consumes, err := mediaTypes(doc["consumes"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed converting consumes: %w", err)
+	}
+	produces, err := mediaTypes(doc["produces"])
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed converting produces: %w", err)
+	}
+
+	c := &swaggerConverter{
+		doc:      doc,
+		consumes: consumes,
+		produces: produces,
+		sources:  make(sources),
+	}
+
+	out := Map{"openapi": "3.0.3"}
+	for key, val := range doc {
+		switch key {
+		case "info", "tags", "externalDocs", "security":
+			out[key] = convertValue(val)
+		default:
+			if isExtension(key) {
+				out[key] = convertValue(val)
+			}
+		}
+	}
+
+	if servers := c.servers(); len(servers) > 0 {
+		out["servers"] = servers
+	}
+
+	components, err := c.components()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(components) > 0 {
+		out["components"] = components
+	}
+
+	paths, err := c.paths()
+	if err != nil {
+		return nil, nil, err
+	}
+	out["paths"] = paths
+
+	return out, c.sources, nil
+}
+
+// moved records that the value at pointer to of the converted document was
+// converted from the one at pointer from
+func (c *swaggerConverter) moved(to, from string) {
+	if to != from {
+		c.sources[to] = from
+	}
+}
+
+// movedParam records where a converted parameter (or header) comes from,
+// including its schema, whose keys are those of the original parameter
+func (c *swaggerConverter) movedParam(to, from string) {
+	c.moved(to, from)
+	c.moved(to+"/schema", from)
+}
+
+func (c *swaggerConverter) servers() (servers []interface{}) {
+	host := c.doc.Str("host")
+	basePath := c.doc.Str("basePath")
+	if host == "" {
+		if basePath != "" {
+			servers = append(servers, Map{"url": basePath})
+		}
+		return servers
+	}
+
+	schemes := c.doc.Slice("schemes")
+	if len(schemes) == 0 {
+		schemes = []Any{{"https"}}
+	}
+
+	for _, scheme := range schemes {
+		servers = append(servers, Map{
+			"url": fmt.Sprintf("%s://%s%s", scheme.Str(), host, basePath),
+		})
+	}
+
+	return servers
+}
+
+func (c *swaggerConverter) components() (Map, error) {
+	components := Map{}
+
+	if defs, ok := c.doc["definitions"]; ok {
+		components["schemas"] = convertValue(defs)
+		c.moved("/components/schemas", "/definitions")
+	}
+
+	parameters, requestBodies := Map{}, Map{}
+	for _, name := range c.doc.Keys("parameters") {
+		param, _ := c.doc.Get("parameters", name)
+		from := pointerTo("parameters", name)
+		switch param.Str("in") {
+		case "body":
+			requestBodies[name] = c.requestBody(
+				swaggerParam{param, from}, c.consumes, pointerTo("components", "requestBodies", name),
+			)
+		case "formData":
+			// form parameters are merged into the request bodies of the
+			// operations that reference them
+		default:
+			converted, err := convertParam(param)
+			if err != nil {
+				return nil, fmt.Errorf("failed converting parameter %s: %w", name, err)
+			}
+			parameters[name] = converted
+			c.movedParam(pointerTo("components", "parameters", name), from)
+		}
+	}
+	if len(parameters) > 0 {
+		components["parameters"] = parameters
+	}
+	if len(requestBodies) > 0 {
+		components["requestBodies"] = requestBodies
+	}
+
+	if _, ok := c.doc.Get("responses"); ok {
+		responses := Map{}
+		for _, code := range c.doc.Keys("responses") {
+			resp, _ := c.doc.Get("responses", code)
+			converted, err := c.response(
+				resp, c.produces, pointerTo("components", "responses", code), pointerTo("responses", code),
+			)
+			if err != nil {
+				return nil, fmt.Errorf("failed converting response %s: %w", code, err)
+			}
+			responses[code] = converted
+		}
+		components["responses"] = responses
+	}
+
+	if _, ok := c.doc.Get("securityDefinitions"); ok {
+		schemes := Map{}
+		for _, name := range c.doc.Keys("securityDefinitions") {
+			def, _ := c.doc.Get("securityDefinitions", name)
+			scheme, err := convertSecurityScheme(def)
+			if err != nil {
+				return nil, fmt.Errorf("failed converting security definition %s: %w", name, err)
+			}
+			schemes[name] = scheme
+		}
+		components["securitySchemes"] = schemes
+		c.moved("/components/securitySchemes", "/securityDefinitions")
+	}
+
+	return components, nil
+}
+
+func (c *swaggerConverter) paths() (Map, error) {
+	paths := Map{}
+	for _, path := range c.doc.Keys("paths") {
+		item, _ := c.doc.Get("paths", path)
+		itemPointer := pointerTo("paths", path)
+		if ref := item.Str("$ref"); ref != "" {
+			paths[path] = Map{"$ref": convertRef(ref)}
+			continue
+		}
+
+		// body and form parameters declared for all operations of the path
+		// need to become part of each operation's request body
+		var common, bodyParams []swaggerParam
+		for i, val := range item.Slice("parameters") {
+			param := swaggerParam{val, fmt.Sprintf("%s/parameters/%d", itemPointer, i)}
+			target, err := c.resolveParam(param)
+			if err != nil {
+				return nil, fmt.Errorf("failed converting path %s: %w", path, err)
+			}
+
+			switch target.Str("in") {
+			case "body", "formData":
+				bodyParams = append(bodyParams, param)
+			default:
+				common = append(common, param)
+			}
+		}
+
+		newItem := Map{}
+		if len(common) > 0 {
+			params, err := c.convertParams(common, itemPointer+"/parameters")
+			if err != nil {
+				return nil, fmt.Errorf("failed converting path %s: %w", path, err)
+			}
+			newItem["parameters"] = params
+		}
+
+		for _, key := range item.Keys() {
+			val, _ := item.Get(key)
+			switch key {
+			case "get", "put", "post", "delete", "options", "head", "patch":
+				op, err := c.operation(val, itemPointer+"/"+key, bodyParams)
+				if err != nil {
+					return nil, fmt.Errorf("failed converting %s %s: %w", strings.ToUpper(key), path, err)
+				}
+				newItem[key] = op
+			case "parameters":
+			default:
+				newItem[key] = convertValue(val.data)
+			}
+		}
+
+		paths[path] = newItem
+	}
+
+	return paths, nil
+}
+
+func (c *swaggerConverter) operation(op Any, pointer string, bodyParams []swaggerParam) (Map, error) {
+	consumes, produces := c.consumes, c.produces
+	var err error
+	if val, ok := op.Get("consumes"); ok {
+		consumes, err = mediaTypes(val.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed converting consumes: %w", err)
+		}
+	}
+	if val, ok := op.Get("produces"); ok {
+		produces, err = mediaTypes(val.data)
+		if err != nil {
+			return nil, fmt.Errorf("failed converting produces: %w", err)
+		}
+	}
+
+	out := Map{}
+	for _, key := range op.Keys() {
+		val, _ := op.Get(key)
+		switch key {
+		case "parameters", "responses", "consumes", "produces", "schemes":
+		default:
+			out[key] = convertValue(val.data)
+		}
+	}
+
+	params := append([]swaggerParam{}, bodyParams...)
+	for i, val := range op.Slice("parameters") {
+		params = append(params, swaggerParam{val, fmt.Sprintf("%s/parameters/%d", pointer, i)})
+	}
+
+	var nonBody, formParams []swaggerParam
+	var body interface{}
+	for _, param := range params {
+		target, err := c.resolveParam(param)
+		if err != nil {
+			return nil, err
+		}
+
+		switch target.Str("in") {
+		case "body":
+			if ref := param.Str("$ref"); ref != "" {
+				body = Map{"$ref": strings.Replace(
+					ref, "#/parameters/", "#/components/requestBodies/", 1,
+				)}
+				c.moved(pointer+"/requestBody", param.pointer)
+			} else {
+				body = c.requestBody(target, consumes, pointer+"/requestBody")
+			}
+		case "formData":
+			formParams = append(formParams, target)
+		default:
+			nonBody = append(nonBody, param)
+		}
+	}
+
+	if len(formParams) > 0 {
+		form, err := c.formRequestBody(formParams, consumes, pointer+"/requestBody")
+		if err != nil {
+			return nil, err
+		}
+		body = form
+	}
+
+	if len(nonBody) > 0 {
+		converted, err := c.convertParams(nonBody, pointer+"/parameters")
+		if err != nil {
+			return nil, err
+		}
+		out["parameters"] = converted
+	}
+	if body != nil {
+		out["requestBody"] = body
+	}
+
+	responses := Map{}
+	for _, code := range op.Keys("responses") {
+		resp, _ := op.Get("responses", code)
+		respPointer := pointer + pointerTo("responses", code)
+		converted, err := c.response(resp, produces, respPointer, respPointer)
+		if err != nil {
+			return nil, fmt.Errorf("failed converting response %s: %w", code, err)
+		}
+		responses[code] = converted
+	}
+	out["responses"] = responses
+
+	return out, nil
+}
+
+// resolveParam returns the parameter a local reference points to, or the
+// parameter itself if it is not a reference
+func (c *swaggerConverter) resolveParam(param swaggerParam) (swaggerParam, error) {
+	ref := param.Str("$ref")
+	if !strings.HasPrefix(ref, "#/parameters/") {
+		return param, nil
+	}
+
+	target, err := c.doc.Pointer(ref)
+	if err != nil {
+		return param, err
+	}
+
+	return swaggerParam{target, strings.TrimPrefix(ref, "#")}, nil
+}
+
+func (c *swaggerConverter) requestBody(param swaggerParam, consumes []string, pointer string) Map {
+	schema, _ := param.Get("schema")
+
+	c.moved(pointer, param.pointer)
+	content := Map{}
+	for _, mediaType := range consumes {
+		content[mediaType] = Map{"schema": convertValue(schema.data)}
+		c.moved(pointer+pointerTo("content", mediaType, "schema"), param.pointer+"/schema")
+	}
+
+	body := Map{"content": content}
+	if desc := param.Str("description"); desc != "" {
+		body["description"] = desc
+	}
+	if param.Bool("required") {
+		body["required"] = true
+	}
+
+	return body
+}
+
+// response converts a response at pointer from of the Swagger 2.0 document,
+// which is at pointer to in the converted one
+func (c *swaggerConverter) response(resp Any, produces []string, to, from string) (Map, error) {
+	c.moved(to, from)
+	if ref := resp.Str("$ref"); ref != "" {
+		return Map{"$ref": convertRef(ref)}, nil
+	}
+
+	out := Map{"description": resp.Str("description")}
+
+	if schema, ok := resp.Get("schema"); ok {
+		content := Map{}
+		for _, mediaType := range produces {
+			mtPointer := to + pointerTo("content", mediaType)
+			mt := Map{"schema": convertValue(schema.data)}
+			c.moved(mtPointer+"/schema", from+"/schema")
+			if example, ok := resp.Get("examples", mediaType); ok {
+				mt["example"] = convertValue(example.data)
+				c.moved(mtPointer+"/example", from+pointerTo("examples", mediaType))
+			}
+			content[mediaType] = mt
+		}
+		out["content"] = content
+	}
+
+	if _, ok := resp.Get("headers"); ok {
+		headers := Map{}
+		for _, name := range resp.Keys("headers") {
+			header, _ := resp.Get("headers", name)
+			converted, err := convertParam(header)
+			if err != nil {
+				return nil, fmt.Errorf("failed converting header %s: %w", name, err)
+			}
+			delete(converted, "name")
+			delete(converted, "in")
+			headers[name] = converted
+			c.movedParam(to+pointerTo("headers", name), from+pointerTo("headers", name))
+		}
+		out["headers"] = headers
+	}
+
+	for _, key := range resp.Keys() {
+		if isExtension(key) {
+			val, _ := resp.Get(key)
+			out[key] = convertValue(val.data)
+		}
+	}
+
+	return out, nil
+}
+
+// formRequestBody merges formData parameters into a single object schema.
+// Forms that upload files must be encoded as multipart/form-data, others use
+// the encodings the operation consumes.
+func (c *swaggerConverter) formRequestBody(params []swaggerParam, consumes []string, pointer string) (Map, error) {
+	properties := Map{}
+	var required []interface{}
+	multipart := false
+	for _, param := range params {
+		name := param.Str("name")
+		converted, err := convertParam(param.Any)
+		if err != nil {
+			return nil, fmt.Errorf("failed converting parameter %s: %w", name, err)
+		}
+		prop := converted["schema"].(Map)
+		if desc := param.Str("description"); desc != "" {
+			prop["description"] = desc
+		}
+		if prop["format"] == Binary {
+			multipart = true
+		}
+		properties[name] = prop
+
+		if param.Bool("required") {
+			required = append(required, name)
+		}
+	}
+
+	schema := Map{"type": Object, "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+
+	var formTypes []string
+	for _, mediaType := range consumes {
+		switch mediaType {
+		case "multipart/form-data", "application/x-www-form-urlencoded":
+			formTypes = append(formTypes, mediaType)
+		}
+	}
+	if len(formTypes) == 0 || multipart {
+		formTypes = []string{"application/x-www-form-urlencoded"}
+		if multipart {
+			formTypes = []string{"multipart/form-data"}
+		}
+	}
+
+	content := Map{}
+	for _, mediaType := range formTypes {
+		content[mediaType] = Map{"schema": schema}
+		properties := pointer + pointerTo("content", mediaType, "schema", "properties")
+		for _, param := range params {
+			c.moved(properties+pointerTo(param.Str("name")), param.pointer)
+		}
+	}
+
+	return Map{"content": content}, nil
+}
+
+// convertParams converts the non-body parameters of a path or an operation,
+// which are at pointer in the converted document
+func (c *swaggerConverter) convertParams(params []swaggerParam, pointer string) (out []interface{}, err error) {
+	for i, param := range params {
+		c.movedParam(fmt.Sprintf("%s/%d", pointer, i), param.pointer)
+		if ref := param.Str("$ref"); ref != "" {
+			out = append(out, Map{"$ref": convertRef(ref)})
+			continue
+		}
+
+		converted, err := convertParam(param.Any)
+		if err != nil {
+			return nil, fmt.Errorf("failed converting parameter %s: %w", param.Str("name"), err)
+		}
+		out = append(out, converted)
+	}
+
+	return out, nil
+}
+
+// convertParam converts a non-body Swagger 2.0 parameter (or header) into an
+// OpenAPI 3 parameter, moving its type information into a schema and its
+// collection format into a serialization style. Tab separated values have no
+// OpenAPI 3 equivalent.
+func convertParam(param Any) (Map, error) {
+	out, schema := Map{}, Map{}
+	for _, key := range param.Keys() {
+		val, _ := param.Get(key)
+		switch {
+		case swaggerParamSchemaKeys[key]:
+			schema[key] = convertValue(val.data)
+		case key == "collectionFormat":
+		case isExtension(key):
+			// extensions also describe the value (e.g. x-enum-name), so
+			// they're kept in both places
+			out[key] = convertValue(val.data)
+			schema[key] = convertValue(val.data)
+		default:
+			out[key] = convertValue(val.data)
+		}
+	}
+
+	if schema["type"] == "file" {
+		schema["type"], schema["format"] = String, Binary
+	}
+	if items, ok := schema["items"].(Map); ok {
+		delete(items, "collectionFormat")
+	}
+
+	switch param.Str("collectionFormat") {
+	case "csv":
+		if param.Str("in") == "query" || param.Str("in") == "formData" {
+			out["style"], out["explode"] = "form", false
+		} else {
+			out["style"] = "simple"
+		}
+	case "ssv":
+		out["style"] = "spaceDelimited"
+	case "pipes":
+		out["style"] = "pipeDelimited"
+	case "multi":
+		out["style"], out["explode"] = "form", true
+	case "tsv":
+		return nil, fmt.Errorf("collectionFormat tsv is not supported by OpenAPI 3")
+	}
+
+	out["schema"] = schema
+
+	return out, nil
+}
+
+func convertSecurityScheme(def Any) (Map, error) {
+	out := Map{}
+	if desc := def.Str("description"); desc != "" {
+		out["description"] = desc
+	}
+
+	switch def.Str("type") {
+	case "basic":
+		out["type"], out["scheme"] = "http", "basic"
+	case "apiKey":
+		out["type"], out["name"], out["in"] = "apiKey", def.Str("name"), def.Str("in")
+	case "oauth2":
+		flow := Map{}
+		if url := def.Str("authorizationUrl"); url != "" {
+			flow["authorizationUrl"] = url
+		}
+		if url := def.Str("tokenUrl"); url != "" {
+			flow["tokenUrl"] = url
+		}
+		scopes, _ := def.Get("scopes")
+		flow["scopes"] = convertValue(scopes.data)
+		if flow["scopes"] == nil {
+			flow["scopes"] = Map{}
+		}
+
+		var flowName string
+		switch def.Str("flow") {
+		case "implicit":
+			flowName = "implicit"
+		case "password":
+			flowName = "password"
+		case "application":
+			flowName = "clientCredentials"
+		case "accessCode":
+			flowName = "authorizationCode"
+		default:
+			return nil, fmt.Errorf("unsupported OAuth2 flow %q", def.Str("flow"))
+		}
+
+		out["type"], out["flows"] = "oauth2", Map{flowName: flow}
+	default:
+		return nil, fmt.Errorf("unsupported type %q", def.Str("type"))
+	}
+
+	return out, nil
+}
+
+// convertValue deep-copies a value of a Swagger 2.0 document, converting the
+// schema keywords that changed in OpenAPI 3 on the way: references are
+// rewritten to point into components, "x-nullable" becomes "nullable", file
+// types become binary strings and discriminators become objects.
+func convertValue(val interface{}) interface{} {
+	switch v := val.(type) {
+	case Map:
+		out := make(Map, len(v))
+		for key, val := range v {
+			switch {
+			case key == "$ref":
+				if ref, ok := val.(string); ok {
+					out[key] = convertRef(ref)
+					continue
+				}
+			case key == "x-nullable":
+				out["nullable"] = val
+				continue
+			case key == "type" && val == "file":
+				out["type"], out["format"] = String, Binary
+				continue
+			case key == "discriminator":
+				if prop, ok := val.(string); ok {
+					out[key] = Map{"propertyName": prop}
+					continue
+				}
+			}
+
+			out[key] = convertValue(val)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i := range v {
+			out[i] = convertValue(v[i])
+		}
+		return out
+	default:
+		return val
+	}
+}
+
+func convertRef(ref string) string {
+	idx := strings.Index(ref, "#")
+	if idx < 0 {
+		return ref
+	}
+
+	for _, prefix := range swaggerRefPrefixes {
+		if strings.HasPrefix(ref[idx:], prefix[0]) {
+			return ref[:idx] + prefix[1] + strings.TrimPrefix(ref[idx:], prefix[0])
+		}
+	}
+
+	return ref
+}
+
+// mediaTypes returns the list of media types of a "consumes" or "produces"
+// value, defaulting to JSON
+func mediaTypes(val interface{}) ([]string, error) {
+	types, _ := val.([]interface{})
+	if len(types) == 0 {
+		return []string{"application/json"}, nil
+	}
+
+	out := make([]string, len(types))
+	for i, typ := range types {
+		str, ok := typ.(string)
+		if !ok {
+			return nil, fmt.Errorf("media type %v is not a string", typ)
+		}
+		out[i] = str
+	}
+
+	return out, nil
+}
+
+func isExtension(key interface{}) bool {
+	str, _ := key.(string)
+	return strings.HasPrefix(str, "x-")
+}
@@ -0,0 +1,124 @@
+package traverser
+
+import (
+	"testing"
+
+	"github.com/jgroeneveld/trial/assert"
+	"gopkg.in/yaml.v2"
+)
+
+const swaggerDoc = `
+swagger: "2.0"
+info: { version: 1.0.0, title: legacy }
+host: api.example.com
+basePath: /v1
+securityDefinitions:
+    oauth:
+        type: oauth2
+        flow: application
+        tokenUrl: https://example.com/token
+        scopes: { "read:pets": read pets }
+parameters:
+    Limit: { name: limit, in: query, type: integer, format: int64, maximum: 50 }
+paths:
+    /pets:
+        get:
+            operationId: list-pets
+            parameters:
+                - $ref: "#/parameters/Limit"
+                - { name: tags, in: query, type: array, items: { type: string }, collectionFormat: csv }
+            responses:
+                '200':
+                    description: ok
+                    schema: { $ref: "#/definitions/PetList" }
+        post:
+            operationId: create-pet
+            parameters:
+                - { name: pet, in: body, required: true, schema: { $ref: "#/definitions/Pet" } }
+            responses:
+                '201': { description: created }
+    /pets/{id}/photo:
+        put:
+            operationId: upload-photo
+            parameters:
+                - { name: photo, in: formData, type: file, required: true }
+            responses:
+                '204': { description: uploaded }
+definitions:
+    Pet:
+        type: object
+        discriminator: kind
+        properties:
+            kind: { type: string }
+            owner: { type: string, x-nullable: true }
+    PetList:
+        type: object
+        properties:
+            pets: { type: array, items: { $ref: "#/definitions/Pet" } }
+`
+
+func TestConvertSwagger2(t *testing.T) {
+	var doc Map
+	err := yaml.Unmarshal([]byte(swaggerDoc), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+	assert.True(t, IsSwagger2(doc), "document must be detected as Swagger 2.0")
+
+	doc, err = ConvertSwagger2(doc)
+	assert.MustBeNil(t, err, "document must be converted successfully")
+
+	assert.Equal(t, "https://api.example.com/v1", doc.Str("servers", "[0]", "url"))
+	assert.Equal(t, "#/components/parameters/Limit", doc.Str("paths", "/pets", "get", "parameters", "[0]", "$ref"))
+	assert.Equal(t, "int64", doc.Str("components", "parameters", "Limit", "schema", "format"))
+	assert.Equal(t, "form", doc.Str("paths", "/pets", "get", "parameters", "[1]", "style"))
+	assert.Equal(t, "#/components/schemas/Pet", doc.Str(
+		"paths", "/pets", "post", "requestBody", "content", "application/json", "schema", "$ref",
+	))
+	assert.Equal(t, Binary, doc.Str(
+		"paths", "/pets/{id}/photo", "put", "requestBody", "content", "multipart/form-data",
+		"schema", "properties", "photo", "format",
+	))
+	assert.Equal(t, "kind", doc.Str("components", "schemas", "Pet", "discriminator", "propertyName"))
+	assert.Equal(t, "https://example.com/token", doc.Str(
+		"components", "securitySchemes", "oauth", "flows", "clientCredentials", "tokenUrl",
+	))
+
+	api, err := ParseDoc(doc)
+	assert.MustBeNil(t, err, "converted document must be parsed successfully")
+	assert.Equal(t, "Pet", api.Methods[1].InputType)
+	assert.Equal(t, "PetList", api.Methods[0].OutputType)
+	assert.Equal(t, int64(50), api.GetSchema("ListPetsInput").GetParam("limit").Maximum)
+	assert.True(t, api.GetSchema("Pet").GetParam("owner").Nullable, "x-nullable must be converted")
+
+	err = yaml.Unmarshal([]byte(`
+swagger: 2.0
+info: { version: 1.0.0, title: legacy }
+paths:
+    /pets:
+        get:
+            parameters:
+                - { name: tags, in: query, type: array, items: { type: string }, collectionFormat: tsv }
+            responses:
+                '200': { description: ok }
+`), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+	assert.True(t, IsSwagger2(doc), "unquoted versions must be detected")
+
+	_, err = ConvertSwagger2(doc)
+	assert.NotNil(t, err, "tab separated values must be rejected")
+
+	doc = nil
+	err = yaml.Unmarshal([]byte(`
+swagger: "2.0"
+info: { version: 1.0.0, title: legacy }
+paths:
+    /pets:
+        get:
+            produces: [{ type: application/json }]
+            responses:
+                '200': { description: ok, schema: { type: string } }
+`), &doc)
+	assert.MustBeNil(t, err, "document must be valid YAML")
+
+	_, err = ConvertSwagger2(doc)
+	assert.NotNil(t, err, "media types that aren't strings must be rejected")
+}
@@ -1,9 +1,13 @@
 package traverser
 
 import (
+	"fmt"
+	"math"
+	"net/url"
 	"regexp"
 	"sort"
 	"strconv"
+	"strings"
 )
 
 var idxRegex = regexp.MustCompile(`^\[(\d+)\]$`)
@@ -19,7 +23,7 @@ func (doc Map) Get(path ...string) (final Any, ok bool) {
 		return final, false
 	}
 
-	inext, ok := doc[path[0]]
+	inext, ok := lookupKey(doc, path[0])
 	if !ok {
 		return final, false
 	}
@@ -65,7 +69,7 @@ func (any Any) Get(path ...string) (final Any, ok bool) {
 			return final, ok
 		}
 
-		mp, ok := m[path[0]]
+		mp, ok := lookupKey(m, path[0])
 		if !ok {
 			return final, false
 		}
@@ -80,6 +84,32 @@ func (any Any) Get(path ...string) (final Any, ok bool) {
 	return next, true
 }
 
+// keyString returns a key of a map as a string. YAML keys aren't necessarily
+// strings, e.g. unquoted status codes are integers.
+func keyString(key interface{}) string {
+	if str, ok := key.(string); ok {
+		return str
+	}
+
+	return fmt.Sprint(key)
+}
+
+// lookupKey returns the value of a map's key, matching keys that aren't
+// strings by their string representation
+func lookupKey(m Map, key string) (interface{}, bool) {
+	if val, ok := m[key]; ok {
+		return val, true
+	}
+
+	for k, val := range m {
+		if _, isStr := k.(string); !isStr && keyString(k) == key {
+			return val, true
+		}
+	}
+
+	return nil, false
+}
+
 func (doc Map) Str(path ...string) (str string) {
 	sub, ok := doc.Get(path...)
 	if !ok {
@@ -123,6 +153,35 @@ func (any Any) Int64(path ...string) (num int64) {
 		num = int64(v)
 	case int:
 		num = int64(v)
+	case float64:
+		// e.g. 2.0, which JSON documents decode as a float
+		if v == math.Trunc(v) {
+			num = int64(v)
+		}
+	}
+
+	return num
+}
+
+func (any Any) Float64(path ...string) (num float64) {
+	sub, ok := any.Get(path...)
+	if !ok {
+		return num
+	}
+
+	switch v := sub.data.(type) {
+	case float64:
+		num = v
+	case float32:
+		num = float64(v)
+	case int64:
+		num = float64(v)
+	case int32:
+		num = float64(v)
+	case int:
+		num = float64(v)
+	case uint64:
+		num = float64(v)
 	}
 
 	return num
@@ -202,7 +261,7 @@ func (doc Map) Keys(path ...string) []string {
 	strs := make([]string, len(m))
 	var i int
 	for key := range m {
-		strs[i] = key.(string)
+		strs[i] = keyString(key)
 		i++
 	}
 
@@ -225,7 +284,7 @@ func (any Any) Keys(path ...string) []string {
 	strs := make([]string, len(m))
 	var i int
 	for key := range m {
-		strs[i] = key.(string)
+		strs[i] = keyString(key)
 		i++
 	}
 
@@ -233,3 +292,73 @@ func (any Any) Keys(path ...string) []string {
 
 	return strs
 }
+
+// pointerUnescaper unescapes the reference tokens of JSON Pointers, "~1"
+// before "~0" so that "~01" becomes "~1"
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+
+// ParsePointer splits an RFC 6901 JSON Pointer into its unescaped reference
+// tokens. The pointer may be given as a URI fragment, e.g.
+// "#/paths/~1users/get", in which case it is percent-decoded first.
+func ParsePointer(pointer string) ([]string, error) {
+	if strings.HasPrefix(pointer, "#") {
+		unescaped, err := url.PathUnescape(pointer[1:])
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON pointer %q: %w", pointer, err)
+		}
+		pointer = unescaped
+	}
+
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must start with /", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+
+	return tokens, nil
+}
+
+// Pointer returns the value an RFC 6901 JSON Pointer refers to
+func (doc Map) Pointer(pointer string) (Any, error) {
+	return Any{doc}.Pointer(pointer)
+}
+
+// Pointer returns the value an RFC 6901 JSON Pointer refers to, relative to
+// this value
+func (any Any) Pointer(pointer string) (Any, error) {
+	tokens, err := ParsePointer(pointer)
+	if err != nil {
+		return Any{}, err
+	}
+
+	current := any
+	for _, token := range tokens {
+		var next interface{}
+		var ok bool
+
+		switch v := current.data.(type) {
+		case Map:
+			next, ok = lookupKey(v, token)
+		case []interface{}:
+			idx, err := strconv.Atoi(token)
+			if err == nil && idx >= 0 && idx < len(v) && token == strconv.Itoa(idx) {
+				next, ok = v[idx], true
+			}
+		}
+
+		if !ok {
+			return Any{}, fmt.Errorf("JSON pointer %q not found", pointer)
+		}
+
+		current = Any{next}
+	}
+
+	return current, nil
+}
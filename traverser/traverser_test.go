@@ -0,0 +1,49 @@
+package traverser
+
+import (
+	"testing"
+
+	"github.com/jgroeneveld/trial/assert"
+)
+
+func TestPointer(t *testing.T) {
+	doc := Map{
+		"paths": Map{
+			"/users/{id}": Map{"get": Map{"operationId": "getUser"}},
+		},
+		"tags":      []interface{}{Map{"name": "users"}},
+		"a~b":       Map{"c d": "escaped"},
+		"responses": Map{200: "ok"},
+	}
+
+	tokens, err := ParsePointer("#/paths/~1users~1%7Bid%7D/get")
+	assert.MustBeNil(t, err, "pointer must be parsed")
+	assert.DeepEqual(t, []string{"paths", "/users/{id}", "get"}, tokens)
+
+	tokens, err = ParsePointer("/~01")
+	assert.MustBeNil(t, err, "pointer must be parsed")
+	assert.DeepEqual(t, []string{"~1"}, tokens, "~0 must be unescaped after ~1")
+
+	_, err = ParsePointer("paths")
+	assert.NotNil(t, err, "pointers must start with a slash")
+
+	for pointer, expected := range map[string]interface{}{
+		"#/paths/~1users~1{id}/get/operationId": "getUser",
+		"/tags/0/name":                          "users",
+		"#/a~0b/c%20d":                          "escaped",
+		"/responses/200":                        "ok",
+	} {
+		val, err := doc.Pointer(pointer)
+		assert.MustBeNil(t, err, "pointer %s must be found", pointer)
+		assert.Equal(t, expected, val.data, "pointer %s must be resolved", pointer)
+	}
+
+	for _, pointer := range []string{"/tags/1", "/tags/01", "/paths/users", "/tags/0/name/x"} {
+		_, err := doc.Pointer(pointer)
+		assert.NotNil(t, err, "pointer %s must not be found", pointer)
+	}
+
+	root, err := doc.Pointer("")
+	assert.MustBeNil(t, err, "the empty pointer must refer to the whole document")
+	assert.DeepEqual(t, Any{doc}, root)
+}